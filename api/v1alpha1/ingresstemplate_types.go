@@ -19,7 +19,9 @@ package v1alpha1
 import (
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -27,9 +29,16 @@ import (
 
 // IngressTemplateSpec defines the desired state of IngressTemplate
 type IngressTemplateSpec struct {
-	// IngressSpec Template for Ingress.Spec
-	// +kubebuilder:validation:Required
-	IngressSpecTemplate networkingv1.IngressSpec `json:"ingressSpecTemplate"`
+	// IngressSpec Template for Ingress.Spec. Each string field is rendered separately.
+	// TLS entries, rules and paths with a when field are only included when it renders to "true".
+	// +optional
+	IngressSpecTemplate IngressSpecTemplate `json:"ingressSpecTemplate,omitempty"`
+
+	// IngressSpecTemplateYAML Template for Ingress.Spec as a whole YAML document.
+	// It is rendered once and then decoded, so actions such as range and if can generate any number of entries.
+	// Mutually exclusive with IngressSpecTemplate.
+	// +optional
+	IngressSpecTemplateYAML string `json:"ingressSpecTemplateYAML,omitempty"`
 
 	// Annotations This annotation is generated in Ingress
 	// +optional
@@ -38,16 +47,411 @@ type IngressTemplateSpec struct {
 	// Labels This labels is generated in Ingress
 	// +optional
 	IngressLabels map[string]string `json:"ingressLabels,omitempty"`
+
+	// ConfigMapRefs ConfigMaps in the same namespace whose data is available to templates as .ConfigMaps.<name>.<key>
+	// +optional
+	ConfigMapRefs []corev1.LocalObjectReference `json:"configMapRefs,omitempty"`
+
+	// SecretRefs Secrets in the same namespace whose data is available to templates as .Secrets.<name>.<key>
+	// +optional
+	SecretRefs []corev1.LocalObjectReference `json:"secretRefs,omitempty"`
+
+	// StrictMissingKeys Fail rendering when a template refers to a missing key instead of rendering "<no value>".
+	// Defaults to the operator's --strict-missing-keys flag.
+	// +optional
+	StrictMissingKeys *bool `json:"strictMissingKeys,omitempty"`
+
+	// Parameters Values available to templates as .Values
+	// +optional
+	// +kubebuilder:validation:Type=object
+	Parameters *apiextensionsv1.JSON `json:"parameters,omitempty"`
+
+	// ParametersSchema OpenAPI v3 schema that Parameters must satisfy, in the same structural form as the schema of a CRD.
+	// Defaults declared in the schema are applied to Parameters before rendering.
+	// +optional
+	// +kubebuilder:validation:Type=object
+	ParametersSchema *apiextensionsv1.JSON `json:"parametersSchema,omitempty"`
+
+	// Libraries Names of the IngressTemplateLibraries whose named templates are available to templates
+	// +optional
+	Libraries []string `json:"libraries,omitempty"`
+
+	// Delimiters Action delimiters of the templates of this IngressTemplate, such as "[[" and "]]", instead of "{{" and "}}".
+	// Useful when IngressTemplates are shipped in Helm charts, which would otherwise render them first.
+	// +optional
+	Delimiters *Delimiters `json:"delimiters,omitempty"`
+
+	// AutoTLS Derive TLS entries from the rendered hosts of the rules, including their aliases, for the hosts no TLS entry of the template covers.
+	// +optional
+	AutoTLS *AutoTLS `json:"autoTLS,omitempty"`
+
+	// Patches Patches applied in order to the rendered Ingress. Each patch is a template itself.
+	// +optional
+	Patches []Patch `json:"patches,omitempty"`
+
+	// IgnoreDifferences Fields of the Ingress that are left as they are in the cluster once the Ingress exists,
+	// in addition to those the operator ignores for every Ingress.
+	// +optional
+	IgnoreDifferences *IgnoreDifferences `json:"ignoreDifferences,omitempty"`
+
+	// ObserveOnly Render the Ingress and report in status.drift how the Ingress in the cluster differs from it, without ever writing the Ingress.
+	// Defaults to the operator's --observe-only flag.
+	// +optional
+	ObserveOnly *bool `json:"observeOnly,omitempty"`
+
+	// DryRun Render the Ingress without creating or updating it, and publish it in a ConfigMap named in status.preview for review.
+	// Turning DryRun off applies the rendered Ingress.
+	// +optional
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// Engine How the string fields are rendered: GoTemplate renders {{ }} actions, CEL evaluates ${ } expressions.
+	// Libraries and Delimiters can only be used with GoTemplate.
+	// +optional
+	// +kubebuilder:default=GoTemplate
+	Engine Engine `json:"engine,omitempty"`
+}
+
+// Engine Rendering engine of an IngressTemplate
+// +kubebuilder:validation:Enum=GoTemplate;CEL
+type Engine string
+
+const (
+	EngineGoTemplate Engine = "GoTemplate"
+	EngineCEL        Engine = "CEL"
+)
+
+// AutoTLS How TLS entries are derived from the hosts of the rules
+type AutoTLS struct {
+	// SecretName Template of the name of the Secret holding the certificate of a host, which is available as .Host.
+	// A host whose secret name renders to an empty string is not covered by any Secret, which fails the render.
+	// +kubebuilder:validation:MinLength=1
+	SecretName string `json:"secretName"`
+
+	// Grouping BySecret puts all hosts with the same secret name into one TLS entry, PerHost gives every host its own entry.
+	// +optional
+	// +kubebuilder:default=BySecret
+	Grouping AutoTLSGrouping `json:"grouping,omitempty"`
+}
+
+// AutoTLSGrouping How hosts are grouped into TLS entries
+// +kubebuilder:validation:Enum=BySecret;PerHost
+type AutoTLSGrouping string
+
+const (
+	AutoTLSGroupingBySecret AutoTLSGrouping = "BySecret"
+	AutoTLSGroupingPerHost  AutoTLSGrouping = "PerHost"
+)
+
+// IgnoreDifferences Fields of an Ingress that the operator leaves to others.
+// They are set when the Ingress is created, but are neither compared when looking for drift nor overwritten afterwards.
+type IgnoreDifferences struct {
+	// JSONPointers RFC 6901 pointers to fields below /spec, /metadata/labels or /metadata/annotations, such as /spec/defaultBackend
+	// +optional
+	JSONPointers []JSONPointer `json:"jsonPointers,omitempty"`
+
+	// Labels Globs of label keys, such as app.kubernetes.io/*. * matches any characters, ? a single character.
+	// +optional
+	Labels []string `json:"labels,omitempty"`
+
+	// Annotations Globs of annotation keys, such as cert-manager.io/*. * matches any characters, ? a single character.
+	// +optional
+	Annotations []string `json:"annotations,omitempty"`
+}
+
+// JSONPointer RFC 6901 pointer to a field of an Ingress
+// +kubebuilder:validation:Pattern=`^/(spec|metadata/labels|metadata/annotations)(/.*)?$`
+type JSONPointer string
+
+// Patch Patch of the rendered Ingress
+type Patch struct {
+	// Type StrategicMerge for a strategic merge patch, JSONPatch for an RFC 6902 JSON Patch
+	// +optional
+	// +kubebuilder:default=StrategicMerge
+	Type PatchType `json:"type,omitempty"`
+
+	// Patch Template of the patch as YAML or JSON. A patch that renders to an empty document is skipped.
+	// +kubebuilder:validation:MinLength=1
+	Patch string `json:"patch"`
+}
+
+// PatchType Format of a Patch
+// +kubebuilder:validation:Enum=StrategicMerge;JSONPatch
+type PatchType string
+
+const (
+	PatchTypeStrategicMerge PatchType = "StrategicMerge"
+	PatchTypeJSONPatch      PatchType = "JSONPatch"
+)
+
+// Delimiters Action delimiters of templates
+type Delimiters struct {
+	// Left Left action delimiter, such as "[[" or "${"
+	// +kubebuilder:validation:MinLength=1
+	Left string `json:"left"`
+
+	// Right Right action delimiter, such as "]]" or "}"
+	// +kubebuilder:validation:MinLength=1
+	Right string `json:"right"`
+}
+
+// IngressSpecTemplate Template for Ingress.Spec
+type IngressSpecTemplate struct {
+	// IngressClassName Name of the IngressClass of the Ingress
+	// +optional
+	IngressClassName *string `json:"ingressClassName,omitempty"`
+
+	// DefaultBackend Backend of the requests that match no rule
+	// +optional
+	DefaultBackend *networkingv1.IngressBackend `json:"defaultBackend,omitempty"`
+
+	// TLS TLS configurations of the Ingress
+	// +optional
+	TLS []IngressTLSTemplate `json:"tls,omitempty"`
+
+	// Rules Host rules of the Ingress
+	// +optional
+	Rules []IngressRuleTemplate `json:"rules,omitempty"`
+}
+
+// IngressTLSTemplate Template for an IngressTLS
+type IngressTLSTemplate struct {
+	// When Include the entry only when this renders to "true". Always included when empty.
+	// +optional
+	When string `json:"when,omitempty"`
+
+	networkingv1.IngressTLS `json:",inline"`
+}
+
+// IngressRuleTemplate Template for an IngressRule
+type IngressRuleTemplate struct {
+	// When Include the rule only when this renders to "true". Always included when empty.
+	// +optional
+	When string `json:"when,omitempty"`
+
+	// Host Host the rule applies to
+	// +optional
+	Host string `json:"host,omitempty"`
+
+	// Aliases Other hosts the rule applies to. Each alias gets a copy of the rule and is added to the TLS entries holding Host.
+	// Aliases that render to an empty string or repeat a host are ignored.
+	// +optional
+	// +kubebuilder:validation:MaxItems=16
+	Aliases []string `json:"aliases,omitempty"`
+
+	// HTTP Paths of the rule
+	// +optional
+	HTTP *HTTPIngressRuleValueTemplate `json:"http,omitempty"`
+}
+
+// HTTPIngressRuleValueTemplate Template for an HTTPIngressRuleValue
+type HTTPIngressRuleValueTemplate struct {
+	// Paths Paths of the rule
+	// +listType=atomic
+	Paths []HTTPIngressPathTemplate `json:"paths"`
+}
+
+// HTTPIngressPathTemplate Template for an HTTPIngressPath
+type HTTPIngressPathTemplate struct {
+	// When Include the path only when this renders to "true". Always included when empty.
+	// +optional
+	When string `json:"when,omitempty"`
+
+	networkingv1.HTTPIngressPath `json:",inline"`
+}
+
+// MaxAliases is the number of aliases a rule may have.
+const MaxAliases = 16
+
+// IngressSpec returns the IngressSpec t describes, without the TLS entries,
+// rules and paths whose when field is "false". A rule all of whose paths are
+// left out keeps only its host, as an empty list of paths is invalid. Aliases
+// are not expanded.
+func (t *IngressSpecTemplate) IngressSpec() networkingv1.IngressSpec {
+	spec := networkingv1.IngressSpec{
+		IngressClassName: t.IngressClassName,
+		DefaultBackend:   t.DefaultBackend,
+	}
+	for _, tls := range t.TLS {
+		if tls.When != "false" {
+			spec.TLS = append(spec.TLS, tls.IngressTLS)
+		}
+	}
+	for _, rule := range t.Rules {
+		if rule.When == "false" {
+			continue
+		}
+		r := networkingv1.IngressRule{Host: rule.Host}
+		if rule.HTTP != nil {
+			r.HTTP = &networkingv1.HTTPIngressRuleValue{}
+			for _, path := range rule.HTTP.Paths {
+				if path.When != "false" {
+					r.HTTP.Paths = append(r.HTTP.Paths, path.HTTPIngressPath)
+				}
+			}
+			if len(r.HTTP.Paths) == 0 && len(rule.HTTP.Paths) > 0 {
+				r.HTTP = nil
+			}
+		}
+		spec.Rules = append(spec.Rules, r)
+	}
+	return spec
 }
 
 // IngressTemplateStatus defines the observed state of IngressTemplate
 type IngressTemplateStatus struct {
-	// Ready Ingress generation status
+	// Ready Status of the Ready condition. Deprecated: use the Ready condition.
 	Ready corev1.ConditionStatus `json:"ready,omitempty"`
+
+	// ObservedGeneration Generation of the IngressTemplate the status was written for
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions Latest observations of the IngressTemplate's state: Rendered, Applied, Ready and Degraded
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// IngressRef The generated Ingress. Unset until it is created.
+	// +optional
+	IngressRef *IngressReference `json:"ingressRef,omitempty"`
+
+	// Lookups Objects read by the lookup template function in the latest render. The Ingress is rendered again when they change.
+	// +optional
+	Lookups []LookupReference `json:"lookups,omitempty"`
+
+	// RenderError Where the latest render failed. Unset when it succeeded.
+	// +optional
+	RenderError *RenderError `json:"renderError,omitempty"`
+
+	// Drift Fields of the Ingress in the cluster that differ from the rendered ones. Only set in observe-only mode.
+	// +optional
+	Drift *Drift `json:"drift,omitempty"`
+
+	// Preview The Ingress rendered in dry-run mode. Only set while spec.dryRun is true.
+	// +optional
+	Preview *Preview `json:"preview,omitempty"`
+}
+
+// Preview Ingress rendered in dry-run mode
+type Preview struct {
+	// Hash SHA-256 hash of the rendered fields. The Ingress carries it in its ingress-template.takumakume.github.io/rendered-hash annotation once applied.
+	Hash string `json:"hash"`
+
+	// ConfigMapName ConfigMap in the namespace of the IngressTemplate whose ingress.yaml key holds the rendered Ingress
+	ConfigMapName string `json:"configMapName"`
 }
 
+// MaxDriftChanges is the number of changes listed in status.drift.
+const MaxDriftChanges = 20
+
+// Drift How the Ingress in the cluster differs from the rendered one
+type Drift struct {
+	// Count Number of fields that differ
+	Count int `json:"count"`
+
+	// Changes The changes an apply would make, at most 20
+	// +optional
+	Changes []FieldChange `json:"changes,omitempty"`
+}
+
+// FieldChange Change of a field of the Ingress in the cluster an apply would make
+type FieldChange struct {
+	// Path JSON pointer to the field, e.g. /spec/rules/0/host
+	Path string `json:"path"`
+
+	// Operation Add when the Ingress does not have the field, Replace when its value differs
+	Operation FieldOperation `json:"operation"`
+
+	// Live Value of the field in the cluster as JSON, shortened to 128 characters
+	// +optional
+	Live string `json:"live,omitempty"`
+
+	// Rendered Rendered value of the field as JSON, shortened to 128 characters
+	Rendered string `json:"rendered"`
+}
+
+// FieldOperation How an apply changes a field
+// +kubebuilder:validation:Enum=Add;Replace
+type FieldOperation string
+
+const (
+	FieldOperationAdd     FieldOperation = "Add"
+	FieldOperationReplace FieldOperation = "Replace"
+)
+
+// RenderError locates a render error in the IngressTemplate.
+type RenderError struct {
+	// Path JSON path of the field whose template failed, e.g. spec.ingressSpecTemplate.rules[2].host
+	Path string `json:"path"`
+
+	// Line 1-based line of the error in the template of the field
+	// +optional
+	Line int `json:"line,omitempty"`
+
+	// Column 1-based column of the error in the template of the field
+	// +optional
+	Column int `json:"column,omitempty"`
+
+	// Snippet Line of the template the error occurred at
+	// +optional
+	Snippet string `json:"snippet,omitempty"`
+
+	// Message Cause of the error
+	Message string `json:"message"`
+}
+
+// IngressReference identifies the Ingress generated from an IngressTemplate.
+type IngressReference struct {
+	Name string    `json:"name"`
+	UID  types.UID `json:"uid,omitempty"`
+}
+
+// LookupReference identifies an object read by the lookup template function.
+type LookupReference struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace"`
+	Name       string `json:"name"`
+}
+
+const (
+	// ConditionTypeRendered indicates whether the templates could be rendered into an Ingress.
+	ConditionTypeRendered = "Rendered"
+
+	// ConditionTypeApplied indicates whether the rendered Ingress was written to the cluster.
+	ConditionTypeApplied = "Applied"
+
+	// ConditionTypeReady indicates whether the Ingress in the cluster is the one rendered from the current generation.
+	ConditionTypeReady = "Ready"
+
+	// ConditionTypeDegraded indicates that the latest reconcile failed while an Ingress
+	// rendered from an earlier generation or from earlier referenced objects is still in place.
+	ConditionTypeDegraded = "Degraded"
+
+	ReasonRenderSucceeded     = "RenderSucceeded"
+	ReasonRenderFailed        = "RenderFailed"
+	ReasonInvalidParameters   = "InvalidParameters"
+	ReasonRenderLimitExceeded = "RenderLimitExceeded"
+	ReasonPatchFailed         = "PatchFailed"
+
+	ReasonApplySucceeded = "ApplySucceeded"
+	ReasonApplyFailed    = "ApplyFailed"
+	ReasonApplyConflict  = "ApplyConflict"
+	ReasonNotRendered    = "NotRendered"
+	ReasonObserveOnly    = "ObserveOnly"
+	ReasonDryRun         = "DryRun"
+
+	ReasonReconciled        = "Reconciled"
+	ReasonIngressNotCreated = "IngressNotCreated"
+)
+
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+//+kubebuilder:printcolumn:name="Ingress",type=string,JSONPath=`.status.ingressRef.name`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // IngressTemplate is the Schema for the ingresstemplates API
 type IngressTemplate struct {
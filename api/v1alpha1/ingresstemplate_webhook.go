@@ -0,0 +1,71 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"errors"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	"github.com/takumakume/ingress-template-operator/pkg/parameters"
+)
+
+// log is for logging in this package.
+var ingresstemplatelog = logf.Log.WithName("ingresstemplate-resource")
+
+func (r *IngressTemplate) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-ingress-template-takumakume-github-io-v1alpha1-ingresstemplate,mutating=false,failurePolicy=fail,sideEffects=None,groups=ingress-template.takumakume.github.io,resources=ingresstemplates,verbs=create;update,versions=v1alpha1,name=vingresstemplate.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &IngressTemplate{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *IngressTemplate) ValidateCreate() error {
+	ingresstemplatelog.Info("validate create", "name", r.Name)
+
+	return r.validateParameters()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *IngressTemplate) ValidateUpdate(old runtime.Object) error {
+	ingresstemplatelog.Info("validate update", "name", r.Name)
+
+	return r.validateParameters()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *IngressTemplate) ValidateDelete() error {
+	return nil
+}
+
+// validateParameters rejects parameters that do not satisfy the parameters schema.
+func (r *IngressTemplate) validateParameters() error {
+	_, err := parameters.Resolve(r.Spec.ParametersSchema, r.Spec.Parameters)
+	var paramsErr *parameters.Error
+	if errors.As(err, &paramsErr) {
+		return apierrors.NewInvalid(GroupVersion.WithKind("IngressTemplate").GroupKind(), r.Name, paramsErr.Errs)
+	}
+	return err
+}
@@ -0,0 +1,59 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+func TestIngressTemplate_ValidateCreate(t *testing.T) {
+	schema := &apiextensionsv1.JSON{Raw: []byte(`{"type":"object","required":["service"],"properties":{"service":{"type":"string"}}}`)}
+
+	tests := []struct {
+		name        string
+		spec        IngressTemplateSpec
+		wantInvalid bool
+	}{
+		{
+			name: "no parameters",
+			spec: IngressTemplateSpec{},
+		},
+		{
+			name: "valid parameters",
+			spec: IngressTemplateSpec{
+				ParametersSchema: schema,
+				Parameters:       &apiextensionsv1.JSON{Raw: []byte(`{"service":"app"}`)},
+			},
+		},
+		{
+			name: "missing required parameter",
+			spec: IngressTemplateSpec{
+				ParametersSchema: schema,
+			},
+			wantInvalid: true,
+		},
+		{
+			name: "invalid schema",
+			spec: IngressTemplateSpec{
+				ParametersSchema: &apiextensionsv1.JSON{Raw: []byte(`{"type":"string"}`)},
+			},
+			wantInvalid: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &IngressTemplate{Spec: tt.spec}
+			err := r.ValidateCreate()
+			if tt.wantInvalid {
+				if !apierrors.IsInvalid(err) {
+					t.Errorf("ValidateCreate() error = %v, want Invalid", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("ValidateCreate() error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// IngressTemplateLibrarySpec defines the named templates shared by IngressTemplates
+type IngressTemplateLibrarySpec struct {
+	// Templates Template source made of {{ define "name" }} blocks.
+	// IngressTemplates that list this library in spec.libraries can use them with {{ template "name" . }} or {{ include "name" . }}.
+	Templates string `json:"templates"`
+
+	// Delimiters Action delimiters of Templates, instead of "{{" and "}}".
+	// They are independent of the delimiters of the IngressTemplates using this library.
+	// +optional
+	Delimiters *Delimiters `json:"delimiters,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+
+// IngressTemplateLibrary is the Schema for the ingresstemplatelibraries API
+type IngressTemplateLibrary struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec IngressTemplateLibrarySpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// IngressTemplateLibraryList contains a list of IngressTemplateLibrary
+type IngressTemplateLibraryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []IngressTemplateLibrary `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&IngressTemplateLibrary{}, &IngressTemplateLibraryList{})
+}
@@ -22,16 +22,248 @@ limitations under the License.
 package v1alpha1
 
 import (
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AutoTLS) DeepCopyInto(out *AutoTLS) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoTLS.
+func (in *AutoTLS) DeepCopy() *AutoTLS {
+	if in == nil {
+		return nil
+	}
+	out := new(AutoTLS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Delimiters) DeepCopyInto(out *Delimiters) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Delimiters.
+func (in *Delimiters) DeepCopy() *Delimiters {
+	if in == nil {
+		return nil
+	}
+	out := new(Delimiters)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Drift) DeepCopyInto(out *Drift) {
+	*out = *in
+	if in.Changes != nil {
+		in, out := &in.Changes, &out.Changes
+		*out = make([]FieldChange, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Drift.
+func (in *Drift) DeepCopy() *Drift {
+	if in == nil {
+		return nil
+	}
+	out := new(Drift)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FieldChange) DeepCopyInto(out *FieldChange) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FieldChange.
+func (in *FieldChange) DeepCopy() *FieldChange {
+	if in == nil {
+		return nil
+	}
+	out := new(FieldChange)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HTTPIngressPathTemplate) DeepCopyInto(out *HTTPIngressPathTemplate) {
+	*out = *in
+	in.HTTPIngressPath.DeepCopyInto(&out.HTTPIngressPath)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPIngressPathTemplate.
+func (in *HTTPIngressPathTemplate) DeepCopy() *HTTPIngressPathTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTPIngressPathTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HTTPIngressRuleValueTemplate) DeepCopyInto(out *HTTPIngressRuleValueTemplate) {
+	*out = *in
+	if in.Paths != nil {
+		in, out := &in.Paths, &out.Paths
+		*out = make([]HTTPIngressPathTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPIngressRuleValueTemplate.
+func (in *HTTPIngressRuleValueTemplate) DeepCopy() *HTTPIngressRuleValueTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTPIngressRuleValueTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IgnoreDifferences) DeepCopyInto(out *IgnoreDifferences) {
+	*out = *in
+	if in.JSONPointers != nil {
+		in, out := &in.JSONPointers, &out.JSONPointers
+		*out = make([]JSONPointer, len(*in))
+		copy(*out, *in)
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IgnoreDifferences.
+func (in *IgnoreDifferences) DeepCopy() *IgnoreDifferences {
+	if in == nil {
+		return nil
+	}
+	out := new(IgnoreDifferences)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngressReference) DeepCopyInto(out *IngressReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressReference.
+func (in *IngressReference) DeepCopy() *IngressReference {
+	if in == nil {
+		return nil
+	}
+	out := new(IngressReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngressRuleTemplate) DeepCopyInto(out *IngressRuleTemplate) {
+	*out = *in
+	if in.Aliases != nil {
+		in, out := &in.Aliases, &out.Aliases
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.HTTP != nil {
+		in, out := &in.HTTP, &out.HTTP
+		*out = new(HTTPIngressRuleValueTemplate)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressRuleTemplate.
+func (in *IngressRuleTemplate) DeepCopy() *IngressRuleTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(IngressRuleTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngressSpecTemplate) DeepCopyInto(out *IngressSpecTemplate) {
+	*out = *in
+	if in.IngressClassName != nil {
+		in, out := &in.IngressClassName, &out.IngressClassName
+		*out = new(string)
+		**out = **in
+	}
+	if in.DefaultBackend != nil {
+		in, out := &in.DefaultBackend, &out.DefaultBackend
+		*out = new(networkingv1.IngressBackend)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = make([]IngressTLSTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]IngressRuleTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressSpecTemplate.
+func (in *IngressSpecTemplate) DeepCopy() *IngressSpecTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(IngressSpecTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngressTLSTemplate) DeepCopyInto(out *IngressTLSTemplate) {
+	*out = *in
+	in.IngressTLS.DeepCopyInto(&out.IngressTLS)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressTLSTemplate.
+func (in *IngressTLSTemplate) DeepCopy() *IngressTLSTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(IngressTLSTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IngressTemplate) DeepCopyInto(out *IngressTemplate) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressTemplate.
@@ -52,6 +284,84 @@ func (in *IngressTemplate) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngressTemplateLibrary) DeepCopyInto(out *IngressTemplateLibrary) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressTemplateLibrary.
+func (in *IngressTemplateLibrary) DeepCopy() *IngressTemplateLibrary {
+	if in == nil {
+		return nil
+	}
+	out := new(IngressTemplateLibrary)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *IngressTemplateLibrary) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngressTemplateLibraryList) DeepCopyInto(out *IngressTemplateLibraryList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]IngressTemplateLibrary, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressTemplateLibraryList.
+func (in *IngressTemplateLibraryList) DeepCopy() *IngressTemplateLibraryList {
+	if in == nil {
+		return nil
+	}
+	out := new(IngressTemplateLibraryList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *IngressTemplateLibraryList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngressTemplateLibrarySpec) DeepCopyInto(out *IngressTemplateLibrarySpec) {
+	*out = *in
+	if in.Delimiters != nil {
+		in, out := &in.Delimiters, &out.Delimiters
+		*out = new(Delimiters)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressTemplateLibrarySpec.
+func (in *IngressTemplateLibrarySpec) DeepCopy() *IngressTemplateLibrarySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(IngressTemplateLibrarySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IngressTemplateList) DeepCopyInto(out *IngressTemplateList) {
 	*out = *in
@@ -102,6 +412,61 @@ func (in *IngressTemplateSpec) DeepCopyInto(out *IngressTemplateSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.ConfigMapRefs != nil {
+		in, out := &in.ConfigMapRefs, &out.ConfigMapRefs
+		*out = make([]v1.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.SecretRefs != nil {
+		in, out := &in.SecretRefs, &out.SecretRefs
+		*out = make([]v1.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.StrictMissingKeys != nil {
+		in, out := &in.StrictMissingKeys, &out.StrictMissingKeys
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = new(apiextensionsv1.JSON)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ParametersSchema != nil {
+		in, out := &in.ParametersSchema, &out.ParametersSchema
+		*out = new(apiextensionsv1.JSON)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Libraries != nil {
+		in, out := &in.Libraries, &out.Libraries
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Delimiters != nil {
+		in, out := &in.Delimiters, &out.Delimiters
+		*out = new(Delimiters)
+		**out = **in
+	}
+	if in.AutoTLS != nil {
+		in, out := &in.AutoTLS, &out.AutoTLS
+		*out = new(AutoTLS)
+		**out = **in
+	}
+	if in.Patches != nil {
+		in, out := &in.Patches, &out.Patches
+		*out = make([]Patch, len(*in))
+		copy(*out, *in)
+	}
+	if in.IgnoreDifferences != nil {
+		in, out := &in.IgnoreDifferences, &out.IgnoreDifferences
+		*out = new(IgnoreDifferences)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ObserveOnly != nil {
+		in, out := &in.ObserveOnly, &out.ObserveOnly
+		*out = new(bool)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressTemplateSpec.
@@ -117,6 +482,38 @@ func (in *IngressTemplateSpec) DeepCopy() *IngressTemplateSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IngressTemplateStatus) DeepCopyInto(out *IngressTemplateStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.IngressRef != nil {
+		in, out := &in.IngressRef, &out.IngressRef
+		*out = new(IngressReference)
+		**out = **in
+	}
+	if in.Lookups != nil {
+		in, out := &in.Lookups, &out.Lookups
+		*out = make([]LookupReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.RenderError != nil {
+		in, out := &in.RenderError, &out.RenderError
+		*out = new(RenderError)
+		**out = **in
+	}
+	if in.Drift != nil {
+		in, out := &in.Drift, &out.Drift
+		*out = new(Drift)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Preview != nil {
+		in, out := &in.Preview, &out.Preview
+		*out = new(Preview)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressTemplateStatus.
@@ -128,3 +525,63 @@ func (in *IngressTemplateStatus) DeepCopy() *IngressTemplateStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LookupReference) DeepCopyInto(out *LookupReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LookupReference.
+func (in *LookupReference) DeepCopy() *LookupReference {
+	if in == nil {
+		return nil
+	}
+	out := new(LookupReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Patch) DeepCopyInto(out *Patch) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Patch.
+func (in *Patch) DeepCopy() *Patch {
+	if in == nil {
+		return nil
+	}
+	out := new(Patch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Preview) DeepCopyInto(out *Preview) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Preview.
+func (in *Preview) DeepCopy() *Preview {
+	if in == nil {
+		return nil
+	}
+	out := new(Preview)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RenderError) DeepCopyInto(out *RenderError) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RenderError.
+func (in *RenderError) DeepCopy() *RenderError {
+	if in == nil {
+		return nil
+	}
+	out := new(RenderError)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+
+	networkingv1 "k8s.io/api/networking/v1"
+
+	ingresstemplatev1alpha1 "github.com/takumakume/ingress-template-operator/api/v1alpha1"
+	"github.com/takumakume/ingress-template-operator/pkg/render"
+)
+
+// expandAliases adds a copy of each rule of spec for every alias of the rule of
+// the rendered tmpl it comes from, right after the rule, and adds the aliases
+// to the TLS entries holding the host of the rule.
+func expandAliases(spec *networkingv1.IngressSpec, tmpl *ingresstemplatev1alpha1.IngressSpecTemplate) error {
+	hasAliases := false
+	for _, rule := range tmpl.Rules {
+		hasAliases = hasAliases || len(rule.Aliases) > 0
+	}
+	if !hasAliases {
+		return nil
+	}
+
+	rules := make([]networkingv1.IngressRule, 0, len(spec.Rules))
+	i := 0
+	for ii, ruleTemplate := range tmpl.Rules {
+		if ruleTemplate.When == "false" {
+			continue
+		}
+		rule := spec.Rules[i]
+		i++
+		rules = append(rules, rule)
+
+		aliases, err := ruleAliases(fmt.Sprintf("spec.ingressSpecTemplate.rules[%d]", ii), rule.Host, ruleTemplate.Aliases)
+		if err != nil {
+			return err
+		}
+		for _, alias := range aliases {
+			r := *rule.DeepCopy()
+			r.Host = alias
+			rules = append(rules, r)
+		}
+		for t := range spec.TLS {
+			if containsString(spec.TLS[t].Hosts, rule.Host) {
+				for _, alias := range aliases {
+					if !containsString(spec.TLS[t].Hosts, alias) {
+						spec.TLS[t].Hosts = append(spec.TLS[t].Hosts, alias)
+					}
+				}
+			}
+		}
+	}
+	spec.Rules = rules
+	return nil
+}
+
+// ruleAliases returns the rendered aliases of the rule at path with host,
+// without the empty and repeated ones, and checks that they are valid hosts.
+func ruleAliases(path, host string, aliases []string) ([]string, error) {
+	if len(aliases) == 0 {
+		return nil, nil
+	}
+	if host == "" {
+		return nil, &render.FieldError{Path: path + ".aliases", Err: fmt.Errorf("aliases require a host")}
+	}
+
+	var out []string
+	for i, alias := range aliases {
+		if alias == "" || alias == host || containsString(out, alias) {
+			continue
+		}
+		if err := render.ValidateHost(fmt.Sprintf("%s.aliases[%d]", path, i), alias); err != nil {
+			return nil, err
+		}
+		out = append(out, alias)
+	}
+	if len(out) > ingresstemplatev1alpha1.MaxAliases {
+		return nil, &render.FieldError{Path: path + ".aliases", Err: fmt.Errorf("%d aliases, more than the maximum of %d", len(out), ingresstemplatev1alpha1.MaxAliases)}
+	}
+	return out, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
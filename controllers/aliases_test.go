@@ -0,0 +1,146 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+
+	ingresstemplatev1alpha1 "github.com/takumakume/ingress-template-operator/api/v1alpha1"
+	"github.com/takumakume/ingress-template-operator/pkg/render"
+)
+
+func Test_expandAliases(t *testing.T) {
+	backend := networkingv1.IngressRuleValue{
+		HTTP: &networkingv1.HTTPIngressRuleValue{
+			Paths: []networkingv1.HTTPIngressPath{{Path: "/"}},
+		},
+	}
+	tests := []struct {
+		name     string
+		tmpl     ingresstemplatev1alpha1.IngressSpecTemplate
+		spec     networkingv1.IngressSpec
+		want     networkingv1.IngressSpec
+		wantPath string
+	}{
+		{
+			name: "no aliases",
+			tmpl: ingresstemplatev1alpha1.IngressSpecTemplate{
+				Rules: []ingresstemplatev1alpha1.IngressRuleTemplate{{Host: "example.com"}},
+			},
+			spec: networkingv1.IngressSpec{
+				Rules: []networkingv1.IngressRule{{Host: "example.com"}},
+			},
+			want: networkingv1.IngressSpec{
+				Rules: []networkingv1.IngressRule{{Host: "example.com"}},
+			},
+		},
+		{
+			name: "aliases",
+			tmpl: ingresstemplatev1alpha1.IngressSpecTemplate{
+				Rules: []ingresstemplatev1alpha1.IngressRuleTemplate{
+					{When: "false", Host: "preview.example.com", Aliases: []string{"www.preview.example.com"}},
+					{Host: "example.com", Aliases: []string{"www.example.com", "", "example.com", "legacy.example.org", "www.example.com"}},
+					{Host: "api.example.com"},
+				},
+			},
+			spec: networkingv1.IngressSpec{
+				TLS: []networkingv1.IngressTLS{
+					{Hosts: []string{"example.com", "legacy.example.org"}, SecretName: "example"},
+					{Hosts: []string{"api.example.com"}, SecretName: "api"},
+				},
+				Rules: []networkingv1.IngressRule{
+					{Host: "example.com", IngressRuleValue: backend},
+					{Host: "api.example.com"},
+				},
+			},
+			want: networkingv1.IngressSpec{
+				TLS: []networkingv1.IngressTLS{
+					{Hosts: []string{"example.com", "legacy.example.org", "www.example.com"}, SecretName: "example"},
+					{Hosts: []string{"api.example.com"}, SecretName: "api"},
+				},
+				Rules: []networkingv1.IngressRule{
+					{Host: "example.com", IngressRuleValue: backend},
+					{Host: "www.example.com", IngressRuleValue: backend},
+					{Host: "legacy.example.org", IngressRuleValue: backend},
+					{Host: "api.example.com"},
+				},
+			},
+		},
+		{
+			name: "invalid alias",
+			tmpl: ingresstemplatev1alpha1.IngressSpecTemplate{
+				Rules: []ingresstemplatev1alpha1.IngressRuleTemplate{
+					{When: "false", Host: "preview.example.com"},
+					{Host: "example.com", Aliases: []string{"www.example.com", "www_example.com"}},
+				},
+			},
+			spec: networkingv1.IngressSpec{
+				Rules: []networkingv1.IngressRule{{Host: "example.com"}},
+			},
+			wantPath: "spec.ingressSpecTemplate.rules[1].aliases[1]",
+		},
+		{
+			name: "aliases without a host",
+			tmpl: ingresstemplatev1alpha1.IngressSpecTemplate{
+				Rules: []ingresstemplatev1alpha1.IngressRuleTemplate{{Aliases: []string{"www.example.com"}}},
+			},
+			spec: networkingv1.IngressSpec{
+				Rules: []networkingv1.IngressRule{{}},
+			},
+			wantPath: "spec.ingressSpecTemplate.rules[0].aliases",
+		},
+		{
+			name: "too many aliases",
+			tmpl: ingresstemplatev1alpha1.IngressSpecTemplate{
+				Rules: []ingresstemplatev1alpha1.IngressRuleTemplate{{
+					Host: "example.com",
+					Aliases: []string{
+						"a.example.com", "b.example.com", "c.example.com", "d.example.com", "e.example.com", "f.example.com",
+						"g.example.com", "h.example.com", "i.example.com", "j.example.com", "k.example.com", "l.example.com",
+						"m.example.com", "n.example.com", "o.example.com", "p.example.com", "q.example.com",
+					},
+				}},
+			},
+			spec: networkingv1.IngressSpec{
+				Rules: []networkingv1.IngressRule{{Host: "example.com"}},
+			},
+			wantPath: "spec.ingressSpecTemplate.rules[0].aliases",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := expandAliases(&tt.spec, &tt.tmpl)
+			if tt.wantPath != "" {
+				var fieldErr *render.FieldError
+				if !errors.As(err, &fieldErr) || fieldErr.Path != tt.wantPath {
+					t.Fatalf("expandAliases() error = %v, want an error for %s", err, tt.wantPath)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expandAliases() error = %v", err)
+			}
+			if !reflect.DeepEqual(tt.spec, tt.want) {
+				t.Errorf("expandAliases() = %+v, want %+v", tt.spec, tt.want)
+			}
+		})
+	}
+}
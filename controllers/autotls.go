@@ -0,0 +1,87 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	networkingv1 "k8s.io/api/networking/v1"
+
+	ingresstemplatev1alpha1 "github.com/takumakume/ingress-template-operator/api/v1alpha1"
+	"github.com/takumakume/ingress-template-operator/pkg/render"
+)
+
+const autoTLSSecretNamePath = "spec.autoTLS.secretName"
+
+// autoTLS adds TLS entries to spec for the hosts of its rules that no TLS entry
+// covers yet, with the secret names rendered from auto.SecretName.
+func autoTLS(ctx context.Context, spec *networkingv1.IngressSpec, auto *ingresstemplatev1alpha1.AutoTLS, opt render.Options) error {
+	var hosts []string
+	for _, rule := range spec.Rules {
+		if rule.Host == "" || containsString(hosts, rule.Host) || tlsCovers(spec.TLS, rule.Host) {
+			continue
+		}
+		hosts = append(hosts, rule.Host)
+	}
+	if len(hosts) == 0 {
+		return nil
+	}
+
+	secretNames, err := render.RenderHosts(ctx, autoTLSSecretNamePath, auto.SecretName, hosts, opt)
+	if err != nil {
+		return err
+	}
+
+	bySecret := map[string]int{}
+	for i, host := range hosts {
+		name := secretNames[i]
+		if name == "" {
+			return &render.FieldError{Path: autoTLSSecretNamePath, Err: fmt.Errorf("host %q is not covered by any Secret: its secret name renders to an empty string", host)}
+		}
+		if err := render.ValidateSecretName(autoTLSSecretNamePath, name); err != nil {
+			return err
+		}
+		if auto.Grouping != ingresstemplatev1alpha1.AutoTLSGroupingPerHost {
+			if j, ok := bySecret[name]; ok {
+				spec.TLS[j].Hosts = append(spec.TLS[j].Hosts, host)
+				continue
+			}
+			bySecret[name] = len(spec.TLS)
+		}
+		spec.TLS = append(spec.TLS, networkingv1.IngressTLS{Hosts: []string{host}, SecretName: name})
+	}
+	return nil
+}
+
+// tlsCovers reports whether one of the TLS entries holds host, or a wildcard
+// host matching it.
+func tlsCovers(tls []networkingv1.IngressTLS, host string) bool {
+	wildcard := ""
+	if i := strings.IndexByte(host, '.'); i > 0 && !strings.HasPrefix(host, "*.") {
+		wildcard = "*" + host[i:]
+	}
+	for _, t := range tls {
+		for _, h := range t.Hosts {
+			if h == host || (wildcard != "" && h == wildcard) {
+				return true
+			}
+		}
+	}
+	return false
+}
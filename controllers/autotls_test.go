@@ -0,0 +1,117 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+
+	ingresstemplatev1alpha1 "github.com/takumakume/ingress-template-operator/api/v1alpha1"
+	"github.com/takumakume/ingress-template-operator/pkg/render"
+)
+
+func Test_autoTLS(t *testing.T) {
+	rules := []networkingv1.IngressRule{
+		{Host: "example.com"},
+		{Host: "www.example.com"},
+		{Host: "api.example.org"},
+		{Host: "www.example.com"},
+		{},
+	}
+	tests := []struct {
+		name     string
+		auto     ingresstemplatev1alpha1.AutoTLS
+		tls      []networkingv1.IngressTLS
+		want     []networkingv1.IngressTLS
+		wantPath string
+	}{
+		{
+			name: "by secret",
+			auto: ingresstemplatev1alpha1.AutoTLS{
+				SecretName: `{{ .Host | splitList "." | reverse | first }}-{{ .Host | splitList "." | reverse | rest | first }}-tls`,
+			},
+			want: []networkingv1.IngressTLS{
+				{Hosts: []string{"example.com", "www.example.com"}, SecretName: "com-example-tls"},
+				{Hosts: []string{"api.example.org"}, SecretName: "org-example-tls"},
+			},
+		},
+		{
+			name: "per host",
+			auto: ingresstemplatev1alpha1.AutoTLS{
+				SecretName: "shared-tls",
+				Grouping:   ingresstemplatev1alpha1.AutoTLSGroupingPerHost,
+			},
+			want: []networkingv1.IngressTLS{
+				{Hosts: []string{"example.com"}, SecretName: "shared-tls"},
+				{Hosts: []string{"www.example.com"}, SecretName: "shared-tls"},
+				{Hosts: []string{"api.example.org"}, SecretName: "shared-tls"},
+			},
+		},
+		{
+			name: "hosts covered by the template",
+			auto: ingresstemplatev1alpha1.AutoTLS{
+				SecretName: "{{ .Host }}",
+			},
+			tls: []networkingv1.IngressTLS{
+				{Hosts: []string{"*.example.com"}, SecretName: "wildcard"},
+				{Hosts: []string{"api.example.org"}, SecretName: "api"},
+			},
+			want: []networkingv1.IngressTLS{
+				{Hosts: []string{"*.example.com"}, SecretName: "wildcard"},
+				{Hosts: []string{"api.example.org"}, SecretName: "api"},
+				{Hosts: []string{"example.com"}, SecretName: "example.com"},
+			},
+		},
+		{
+			name: "host without a secret",
+			auto: ingresstemplatev1alpha1.AutoTLS{
+				SecretName: `{{ if hasSuffix ".com" .Host }}com-tls{{ end }}`,
+			},
+			wantPath: "spec.autoTLS.secretName",
+		},
+		{
+			name: "invalid secret name",
+			auto: ingresstemplatev1alpha1.AutoTLS{
+				SecretName: "{{ .Host }}_tls",
+			},
+			wantPath: "spec.autoTLS.secretName",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := &networkingv1.IngressSpec{TLS: tt.tls, Rules: rules}
+			err := autoTLS(context.Background(), spec, &tt.auto, render.Options{})
+			if tt.wantPath != "" {
+				var fieldErr *render.FieldError
+				if !errors.As(err, &fieldErr) || fieldErr.Path != tt.wantPath {
+					t.Fatalf("autoTLS() error = %v, want an error for %s", err, tt.wantPath)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("autoTLS() error = %v", err)
+			}
+			if !reflect.DeepEqual(spec.TLS, tt.want) {
+				t.Errorf("autoTLS() = %+v, want %+v", spec.TLS, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,393 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	ingresstemplatev1alpha1 "github.com/takumakume/ingress-template-operator/api/v1alpha1"
+)
+
+// renderedHashAnnotation records on the Ingress the hash of the fields the
+// operator rendered, ignored fields aside. An Ingress whose hash matches the
+// rendered one but whose fields do not was changed by someone else.
+const renderedHashAnnotation = "ingress-template.takumakume.github.io/rendered-hash"
+
+// Reasons of the Events of corrected and of observed drift.
+const (
+	eventReasonDriftCorrected = "DriftCorrected"
+	eventReasonDriftDetected  = "DriftDetected"
+)
+
+var driftCorrections = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "ingresstemplate_drift_corrections_total",
+	Help: "Number of times fields of a generated Ingress changed by someone else were set back to the rendered values.",
+}, []string{"namespace", "name"})
+
+var driftedTemplates = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "ingresstemplate_drifted",
+	Help: "Whether the Ingress of an IngressTemplate in observe-only mode differs from the rendered one (1) or not (0).",
+}, []string{"namespace", "name"})
+
+func init() {
+	metrics.Registry.MustRegister(driftCorrections, driftedTemplates)
+}
+
+// forgetMetrics drops the metrics of the deleted IngressTemplate key.
+func forgetMetrics(key types.NamespacedName) {
+	driftCorrections.DeleteLabelValues(key.Namespace, key.Name)
+	driftedTemplates.DeleteLabelValues(key.Namespace, key.Name)
+}
+
+// ignoreRules are the fields of an Ingress that are left as they are in the
+// cluster once the Ingress exists.
+type ignoreRules struct {
+	pointers    [][]string
+	labels      []*regexp.Regexp
+	annotations []*regexp.Regexp
+}
+
+// newIgnoreRules combines ignores into one set of rules.
+func newIgnoreRules(ignores ...*ingresstemplatev1alpha1.IgnoreDifferences) (*ignoreRules, error) {
+	rules := &ignoreRules{}
+	for _, ignore := range ignores {
+		if ignore == nil {
+			continue
+		}
+		for _, pointer := range ignore.JSONPointers {
+			tokens, err := parseIgnoredPointer(string(pointer))
+			if err != nil {
+				return nil, err
+			}
+			rules.pointers = append(rules.pointers, tokens)
+		}
+		for _, glob := range ignore.Labels {
+			rules.labels = append(rules.labels, globRegexp(glob))
+		}
+		for _, glob := range ignore.Annotations {
+			rules.annotations = append(rules.annotations, globRegexp(glob))
+		}
+	}
+	return rules, nil
+}
+
+// parseIgnoredPointer returns the reference tokens of pointer, which must point
+// below /spec, /metadata/labels or /metadata/annotations.
+func parseIgnoredPointer(pointer string) ([]string, error) {
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("JSON pointer %q does not start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	switch {
+	case tokens[0] == "spec":
+	case len(tokens) >= 2 && tokens[0] == "metadata" && (tokens[1] == "labels" || tokens[1] == "annotations"):
+	default:
+		return nil, fmt.Errorf("JSON pointer %q is not below /spec, /metadata/labels or /metadata/annotations", pointer)
+	}
+	return tokens, nil
+}
+
+// globRegexp matches the keys glob matches. * matches any characters, ? a
+// single character.
+func globRegexp(glob string) *regexp.Regexp {
+	pattern := regexp.QuoteMeta(glob)
+	pattern = strings.ReplaceAll(pattern, `\*`, `.*`)
+	pattern = strings.ReplaceAll(pattern, `\?`, `.`)
+	return regexp.MustCompile("^" + pattern + "$")
+}
+
+// remove removes the ignored fields from obj.
+func (rules *ignoreRules) remove(obj map[string]interface{}) {
+	for _, tokens := range rules.pointers {
+		removePointer(obj, tokens)
+	}
+	rules.forKeys(obj, func(m map[string]interface{}, _, key string) {
+		delete(m, key)
+	})
+}
+
+// preserve sets the ignored fields of obj to their values in live, or removes
+// them when live does not have them. Ignored fields only live has are left out
+// of obj, so that applying it does not take them over from their owners.
+func (rules *ignoreRules) preserve(obj, live map[string]interface{}) {
+	for _, tokens := range rules.pointers {
+		if _, ok := getPointer(obj, tokens); !ok {
+			continue
+		}
+		if value, ok := getPointer(live, tokens); ok {
+			setPointer(obj, tokens, runtime.DeepCopyJSONValue(value))
+		} else {
+			removePointer(obj, tokens)
+		}
+	}
+	rules.forKeys(obj, func(m map[string]interface{}, field, key string) {
+		if value, ok := getPointer(live, []string{"metadata", field, key}); ok {
+			m[key] = runtime.DeepCopyJSONValue(value)
+		} else {
+			delete(m, key)
+		}
+	})
+}
+
+// forKeys calls f with the labels or annotations map of obj and every ignored
+// key of it.
+func (rules *ignoreRules) forKeys(obj map[string]interface{}, f func(m map[string]interface{}, field, key string)) {
+	for field, globs := range map[string][]*regexp.Regexp{"labels": rules.labels, "annotations": rules.annotations} {
+		if len(globs) == 0 {
+			continue
+		}
+		m, _ := getPointer(obj, []string{"metadata", field})
+		keys, _ := m.(map[string]interface{})
+		for key := range keys {
+			if key == renderedHashAnnotation || !matchesAny(globs, key) {
+				continue
+			}
+			f(keys, field, key)
+		}
+	}
+}
+
+func matchesAny(globs []*regexp.Regexp, key string) bool {
+	for _, glob := range globs {
+		if glob.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// getPointer returns the value tokens point to in obj.
+func getPointer(obj interface{}, tokens []string) (interface{}, bool) {
+	for _, token := range tokens {
+		switch v := obj.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if obj, ok = v[token]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			obj = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return obj, true
+}
+
+// setPointer sets the value tokens point to in obj, creating missing objects on
+// the way. Elements of arrays are only replaced, never added.
+func setPointer(obj map[string]interface{}, tokens []string, value interface{}) {
+	var parent interface{} = obj
+	for i, token := range tokens {
+		last := i == len(tokens)-1
+		switch v := parent.(type) {
+		case map[string]interface{}:
+			if last {
+				v[token] = value
+				return
+			}
+			if _, ok := v[token]; !ok {
+				v[token] = map[string]interface{}{}
+			}
+			parent = v[token]
+		case []interface{}:
+			j, err := strconv.Atoi(token)
+			if err != nil || j < 0 || j >= len(v) {
+				return
+			}
+			if last {
+				v[j] = value
+				return
+			}
+			parent = v[j]
+		default:
+			return
+		}
+	}
+}
+
+// removePointer removes the value tokens point to from obj.
+func removePointer(obj map[string]interface{}, tokens []string) {
+	parent, ok := getPointer(obj, tokens[:len(tokens)-1])
+	if !ok {
+		return
+	}
+	token := tokens[len(tokens)-1]
+	switch v := parent.(type) {
+	case map[string]interface{}:
+		delete(v, token)
+	case []interface{}:
+		i, err := strconv.Atoi(token)
+		if err != nil || i < 0 || i >= len(v) {
+			return
+		}
+		grandparent, _ := getPointer(obj, tokens[:len(tokens)-2])
+		remaining := append(v[:i:i], v[i+1:]...)
+		switch g := grandparent.(type) {
+		case map[string]interface{}:
+			g[tokens[len(tokens)-2]] = remaining
+		case []interface{}:
+			j, _ := strconv.Atoi(tokens[len(tokens)-2])
+			g[j] = remaining
+		}
+	}
+}
+
+// renderedHash returns the hash of obj.
+func renderedHash(obj map[string]interface{}) (string, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// fieldChange is a field of obj that live does not have with the same value.
+type fieldChange struct {
+	path      string
+	operation ingresstemplatev1alpha1.FieldOperation
+	live      interface{}
+	rendered  interface{}
+}
+
+// diffFields appends to changes the fields of obj below path that live does
+// not have with the same values. Arrays of a different length are changed as
+// a whole. Fields only live has are not changes, as they belong to others.
+func diffFields(changes []fieldChange, path string, live interface{}, hasLive bool, obj interface{}) []fieldChange {
+	if !hasLive {
+		return append(changes, fieldChange{path: path, operation: ingresstemplatev1alpha1.FieldOperationAdd, rendered: obj})
+	}
+	replace := fieldChange{path: path, operation: ingresstemplatev1alpha1.FieldOperationReplace, live: live, rendered: obj}
+	switch o := obj.(type) {
+	case map[string]interface{}:
+		l, ok := live.(map[string]interface{})
+		if !ok {
+			return append(changes, replace)
+		}
+		keys := make([]string, 0, len(o))
+		for key := range o {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			liveValue, ok := l[key]
+			token := strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+			changes = diffFields(changes, path+"/"+token, liveValue, ok, o[key])
+		}
+	case []interface{}:
+		l, ok := live.([]interface{})
+		if !ok || len(l) != len(o) {
+			return append(changes, replace)
+		}
+		for i := range o {
+			changes = diffFields(changes, path+"/"+strconv.Itoa(i), l[i], true, o[i])
+		}
+	default:
+		if !reflect.DeepEqual(live, obj) {
+			return append(changes, replace)
+		}
+	}
+	return changes
+}
+
+// ingressChanges returns the changes applying obj makes to live, which is nil
+// when there is no Ingress. The rendered hash annotation is bookkeeping of the
+// operator and not a change.
+func ingressChanges(live, obj map[string]interface{}) []fieldChange {
+	obj = runtime.DeepCopyJSON(obj)
+	removePointer(obj, []string{"metadata", "annotations", renderedHashAnnotation})
+	if annotations, ok := getPointer(obj, []string{"metadata", "annotations"}); ok {
+		if m, _ := annotations.(map[string]interface{}); len(m) == 0 {
+			removePointer(obj, []string{"metadata", "annotations"})
+		}
+	}
+	var changes []fieldChange
+	for _, field := range []string{"metadata", "spec"} {
+		liveValue, ok := live[field]
+		changes = diffFields(changes, "/"+field, liveValue, ok, obj[field])
+	}
+	return changes
+}
+
+// maxDriftValueLength is the length values in status.drift are shortened to.
+const maxDriftValueLength = 128
+
+// driftSummary summarizes changes for the status, hiding secret values.
+func driftSummary(changes []fieldChange, redact *redactor) *ingresstemplatev1alpha1.Drift {
+	drift := &ingresstemplatev1alpha1.Drift{Count: len(changes)}
+	for i, change := range changes {
+		if i == ingresstemplatev1alpha1.MaxDriftChanges {
+			break
+		}
+		drift.Changes = append(drift.Changes, ingresstemplatev1alpha1.FieldChange{
+			Path:      change.path,
+			Operation: change.operation,
+			Live:      driftValue(change.live, redact),
+			Rendered:  driftValue(change.rendered, redact),
+		})
+	}
+	return drift
+}
+
+func driftValue(value interface{}, redact *redactor) string {
+	if value == nil {
+		return ""
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	s := redact.String(string(data))
+	if len(s) > maxDriftValueLength {
+		s = s[:maxDriftValueLength-3] + "..."
+	}
+	return s
+}
+
+// driftMessage describes drift in an Event.
+func driftMessage(drift *ingresstemplatev1alpha1.Drift) string {
+	paths := make([]string, 0, len(drift.Changes))
+	for _, change := range drift.Changes {
+		paths = append(paths, change.Path)
+	}
+	if len(drift.Changes) < drift.Count {
+		paths = append(paths, "...")
+	}
+	return fmt.Sprintf("%d fields of the Ingress differ from the rendered ones and are not written in observe-only mode: %s",
+		drift.Count, strings.Join(paths, ", "))
+}
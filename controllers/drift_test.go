@@ -0,0 +1,388 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	ingresstemplatev1alpha1 "github.com/takumakume/ingress-template-operator/api/v1alpha1"
+)
+
+func Test_parseIgnoredPointer(t *testing.T) {
+	tests := []struct {
+		pointer string
+		want    []string
+		wantErr bool
+	}{
+		{pointer: "/spec/defaultBackend", want: []string{"spec", "defaultBackend"}},
+		{pointer: "/metadata/annotations/cert-manager.io~1issuer", want: []string{"metadata", "annotations", "cert-manager.io/issuer"}},
+		{pointer: "/metadata/labels/a~0b", want: []string{"metadata", "labels", "a~b"}},
+		{pointer: "/metadata/name", wantErr: true},
+		{pointer: "spec", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pointer, func(t *testing.T) {
+			got, err := parseIgnoredPointer(tt.pointer)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseIgnoredPointer() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseIgnoredPointer() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ignoreRules_preserve(t *testing.T) {
+	rendered := func() map[string]interface{} {
+		return map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"annotations": map[string]interface{}{"a": "rendered", "cert-manager.io/issuer": "rendered"},
+			},
+			"spec": map[string]interface{}{
+				"ingressClassName": "rendered",
+				"rules": []interface{}{
+					map[string]interface{}{"host": "a.example.com"},
+					map[string]interface{}{"host": "b.example.com"},
+				},
+			},
+		}
+	}
+	live := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{"a": "live", "cert-manager.io/secret": "live"},
+		},
+		"spec": map[string]interface{}{
+			"defaultBackend": map[string]interface{}{"resource": map[string]interface{}{"name": "live"}},
+			"rules": []interface{}{
+				map[string]interface{}{"host": "a.example.com"},
+				map[string]interface{}{"host": "live.example.com"},
+			},
+		},
+	}
+	tests := []struct {
+		name   string
+		ignore ingresstemplatev1alpha1.IgnoreDifferences
+		want   map[string]interface{}
+	}{
+		{
+			name: "nothing ignored",
+			want: rendered(),
+		},
+		{
+			name:   "field missing in live",
+			ignore: ingresstemplatev1alpha1.IgnoreDifferences{JSONPointers: []ingresstemplatev1alpha1.JSONPointer{"/spec/ingressClassName"}},
+			want: map[string]interface{}{
+				"metadata": rendered()["metadata"],
+				"spec": map[string]interface{}{
+					"rules": rendered()["spec"].(map[string]interface{})["rules"],
+				},
+			},
+		},
+		{
+			name:   "field only in live",
+			ignore: ingresstemplatev1alpha1.IgnoreDifferences{JSONPointers: []ingresstemplatev1alpha1.JSONPointer{"/spec/defaultBackend"}},
+			want:   rendered(),
+		},
+		{
+			name:   "array element",
+			ignore: ingresstemplatev1alpha1.IgnoreDifferences{JSONPointers: []ingresstemplatev1alpha1.JSONPointer{"/spec/rules/1/host"}},
+			want: map[string]interface{}{
+				"metadata": rendered()["metadata"],
+				"spec": map[string]interface{}{
+					"ingressClassName": "rendered",
+					"rules": []interface{}{
+						map[string]interface{}{"host": "a.example.com"},
+						map[string]interface{}{"host": "live.example.com"},
+					},
+				},
+			},
+		},
+		{
+			name:   "annotation globs",
+			ignore: ingresstemplatev1alpha1.IgnoreDifferences{Annotations: []string{"cert-manager.io/*"}},
+			want: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]interface{}{"a": "rendered"},
+				},
+				"spec": rendered()["spec"],
+			},
+		},
+		{
+			name:   "annotation in live",
+			ignore: ingresstemplatev1alpha1.IgnoreDifferences{Annotations: []string{"a"}},
+			want: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]interface{}{"a": "live", "cert-manager.io/issuer": "rendered"},
+				},
+				"spec": rendered()["spec"],
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := newIgnoreRules(&tt.ignore)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := rendered()
+			rules.preserve(got, live)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("preserve() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_diffFields(t *testing.T) {
+	tests := []struct {
+		name string
+		live interface{}
+		obj  interface{}
+		want []string
+	}{
+		{name: "extra fields in live", live: map[string]interface{}{"a": "1", "b": "2"}, obj: map[string]interface{}{"a": "1"}},
+		{name: "changed value", live: map[string]interface{}{"a": "2"}, obj: map[string]interface{}{"a": "1"}, want: []string{"Replace /a"}},
+		{name: "missing field", live: map[string]interface{}{}, obj: map[string]interface{}{"a/b": "1"}, want: []string{"Add /a~1b"}},
+		{name: "extra array element", live: []interface{}{"a", "b"}, obj: []interface{}{"a"}, want: []string{"Replace "}},
+		{name: "defaulted field of an element", live: []interface{}{map[string]interface{}{"a": "1", "b": "2"}}, obj: []interface{}{map[string]interface{}{"a": "1"}}},
+		{name: "changed element", live: []interface{}{map[string]interface{}{"a": "1"}, "b"}, obj: []interface{}{map[string]interface{}{"a": "2"}, "c"}, want: []string{"Replace /0/a", "Replace /1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, change := range diffFields(nil, "", tt.live, true, tt.obj) {
+				got = append(got, string(change.operation)+" "+change.path)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffFields() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_driftSummary(t *testing.T) {
+	var changes []fieldChange
+	for i := 0; i < ingresstemplatev1alpha1.MaxDriftChanges+1; i++ {
+		changes = append(changes, fieldChange{path: "/spec/rules", operation: ingresstemplatev1alpha1.FieldOperationReplace, live: "s3cr3t", rendered: strings.Repeat("a", 200)})
+	}
+	got := driftSummary(changes, newRedactor(map[string]map[string]string{"creds": {"password": "s3cr3t"}}, nil))
+	if got.Count != len(changes) || len(got.Changes) != ingresstemplatev1alpha1.MaxDriftChanges {
+		t.Fatalf("driftSummary() lists %d of %d changes, want %d of %d", len(got.Changes), got.Count, ingresstemplatev1alpha1.MaxDriftChanges, len(changes))
+	}
+	if strings.Contains(got.Changes[0].Live, "s3cr3t") {
+		t.Errorf("driftSummary() live = %s, want the secret value hidden", got.Changes[0].Live)
+	}
+	if len(got.Changes[0].Rendered) != maxDriftValueLength {
+		t.Errorf("driftSummary() rendered is %d characters long, want %d", len(got.Changes[0].Rendered), maxDriftValueLength)
+	}
+}
+
+func TestIngressTemplateReconciler_Reconcile_drift(t *testing.T) {
+	ingresstemplate := &ingresstemplatev1alpha1.IngressTemplate{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "drift", Name: "app"},
+		Spec: ingresstemplatev1alpha1.IngressTemplateSpec{
+			IngressAnnotations: map[string]string{"external-dns.alpha.kubernetes.io/hostname": "rendered.example.com"},
+			IngressSpecTemplate: ingresstemplatev1alpha1.IngressSpecTemplate{
+				Rules: []ingresstemplatev1alpha1.IngressRuleTemplate{{Host: "{{ .Metadata.Name }}.example.com"}},
+			},
+			IgnoreDifferences: &ingresstemplatev1alpha1.IgnoreDifferences{Annotations: []string{"external-dns.alpha.kubernetes.io/*"}},
+		},
+	}
+	r, c, _ := newFakeReconciler(t,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "drift"}},
+		ingresstemplate,
+	)
+	r.ForceConflicts = true
+	key := client.ObjectKeyFromObject(ingresstemplate)
+	corrections := driftCorrections.WithLabelValues("drift", "app")
+
+	reconcile := func() *networkingv1.Ingress {
+		t.Helper()
+		if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key}); err != nil {
+			t.Fatal(err)
+		}
+		ingress := &networkingv1.Ingress{}
+		if err := c.Get(context.Background(), key, ingress); err != nil {
+			t.Fatal(err)
+		}
+		return ingress
+	}
+
+	reconcile()
+	ingress := reconcile()
+	if got := testutil.ToFloat64(corrections); got != 0 {
+		t.Errorf("drift corrections after unchanged reconciles = %v, want 0", got)
+	}
+
+	ingress.Spec.Rules[0].Host = "changed.example.com"
+	ingress.Annotations["external-dns.alpha.kubernetes.io/hostname"] = "app.example.com"
+	if err := c.Update(context.Background(), ingress); err != nil {
+		t.Fatal(err)
+	}
+	ingress = reconcile()
+	if got := ingress.Spec.Rules[0].Host; got != "app.example.com" {
+		t.Errorf("host = %s, want the drift corrected to app.example.com", got)
+	}
+	if got := ingress.Annotations["external-dns.alpha.kubernetes.io/hostname"]; got != "app.example.com" {
+		t.Errorf("ignored annotation = %q, want the live app.example.com preserved", got)
+	}
+	if got := testutil.ToFloat64(corrections); got != 1 {
+		t.Errorf("drift corrections = %v, want 1", got)
+	}
+
+	ingress.Annotations["external-dns.alpha.kubernetes.io/hostname"] = "other.example.com"
+	if err := c.Update(context.Background(), ingress); err != nil {
+		t.Fatal(err)
+	}
+	reconcile()
+	if got := testutil.ToFloat64(corrections); got != 1 {
+		t.Errorf("drift corrections after changing an ignored annotation = %v, want 1", got)
+	}
+}
+
+func TestIngressTemplateReconciler_Reconcile_observeOnly(t *testing.T) {
+	handEdited := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "observe", Name: "app"},
+		Spec: networkingv1.IngressSpec{
+			Rules: []networkingv1.IngressRule{{Host: "hand-edited.example.com"}},
+		},
+	}
+	ingresstemplate := &ingresstemplatev1alpha1.IngressTemplate{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "observe", Name: "app"},
+		Spec: ingresstemplatev1alpha1.IngressTemplateSpec{
+			IngressSpecTemplate: ingresstemplatev1alpha1.IngressSpecTemplate{
+				Rules: []ingresstemplatev1alpha1.IngressRuleTemplate{{Host: "{{ .Metadata.Name }}.example.com"}},
+			},
+		},
+	}
+	r, c, recorder := newFakeReconciler(t,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "observe"}},
+		ingresstemplate,
+		handEdited.DeepCopy(),
+	)
+	r.ObserveOnly = true
+	key := client.ObjectKeyFromObject(ingresstemplate)
+
+	for i := 0; i < 2; i++ {
+		if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ingress := &networkingv1.Ingress{}
+	if err := c.Get(context.Background(), key, ingress); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ingress.Spec, handEdited.Spec) || len(ingress.OwnerReferences) > 0 {
+		t.Errorf("Ingress = %+v, want it left unchanged", ingress)
+	}
+
+	got := &ingresstemplatev1alpha1.IngressTemplate{}
+	if err := c.Get(context.Background(), key, got); err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, change := range got.Status.Drift.Changes {
+		if change.Path == "/spec/rules/0/host" {
+			found = true
+			if change.Live != `"hand-edited.example.com"` || change.Rendered != `"app.example.com"` {
+				t.Errorf("change of the host = %+v, want hand-edited.example.com replaced with app.example.com", change)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("status.drift = %+v, want the host changed", got.Status.Drift)
+	}
+	applied := meta.FindStatusCondition(got.Status.Conditions, ingresstemplatev1alpha1.ConditionTypeApplied)
+	if applied == nil || applied.Status != metav1.ConditionFalse || applied.Reason != ingresstemplatev1alpha1.ReasonObserveOnly {
+		t.Errorf("Applied condition = %+v, want False with reason ObserveOnly", applied)
+	}
+	if got := testutil.ToFloat64(driftedTemplates.WithLabelValues("observe", "app")); got != 1 {
+		t.Errorf("drifted gauge = %v, want 1", got)
+	}
+	if len(recorder.Events) != 1 {
+		t.Errorf("recorded %d events, want one DriftDetected event for unchanged drift", len(recorder.Events))
+	}
+}
+
+func TestIngressTemplateReconciler_Reconcile_observeOnlyMatching(t *testing.T) {
+	ingresstemplate := &ingresstemplatev1alpha1.IngressTemplate{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "matching", Name: "app"},
+		Spec: ingresstemplatev1alpha1.IngressTemplateSpec{
+			IngressSpecTemplate: ingresstemplatev1alpha1.IngressSpecTemplate{
+				Rules: []ingresstemplatev1alpha1.IngressRuleTemplate{{Host: "{{ .Metadata.Name }}.example.com"}},
+			},
+		},
+	}
+	r, c, recorder := newFakeReconciler(t,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "matching"}},
+		ingresstemplate,
+	)
+	key := client.ObjectKeyFromObject(ingresstemplate)
+
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatal(err)
+	}
+	// An Ingress adopted from elsewhere matches the rendered one but has no
+	// rendered hash annotation.
+	ingress := &networkingv1.Ingress{}
+	if err := c.Get(context.Background(), key, ingress); err != nil {
+		t.Fatal(err)
+	}
+	delete(ingress.Annotations, renderedHashAnnotation)
+	if err := c.Update(context.Background(), ingress); err != nil {
+		t.Fatal(err)
+	}
+	for len(recorder.Events) > 0 {
+		<-recorder.Events
+	}
+
+	r.ObserveOnly = true
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := &ingresstemplatev1alpha1.IngressTemplate{}
+	if err := c.Get(context.Background(), key, got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.Drift != nil && got.Status.Drift.Count != 0 {
+		t.Errorf("status.drift = %+v, want no changes", got.Status.Drift)
+	}
+	applied := meta.FindStatusCondition(got.Status.Conditions, ingresstemplatev1alpha1.ConditionTypeApplied)
+	if applied == nil || applied.Status != metav1.ConditionTrue {
+		t.Errorf("Applied condition = %+v, want True", applied)
+	}
+	if got := testutil.ToFloat64(driftedTemplates.WithLabelValues("matching", "app")); got != 0 {
+		t.Errorf("drifted gauge = %v, want 0", got)
+	}
+	if len(recorder.Events) != 0 {
+		t.Errorf("recorded %d events, want none for a matching Ingress", len(recorder.Events))
+	}
+}
@@ -18,16 +18,30 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"reflect"
+	"strings"
+	"sync"
+	"time"
 
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	ingresstemplatev1alpha1 "github.com/takumakume/ingress-template-operator/api/v1alpha1"
+	"github.com/takumakume/ingress-template-operator/pkg/parameters"
 	"github.com/takumakume/ingress-template-operator/pkg/render"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
@@ -38,13 +52,67 @@ import (
 // IngressTemplateReconciler reconciles a IngressTemplate object
 type IngressTemplateReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	// StrictMissingKeys is used for IngressTemplates that do not set spec.strictMissingKeys.
+	StrictMissingKeys bool
+
+	// Cluster holds the operator-wide values available to templates as .Cluster.
+	Cluster render.Cluster
+
+	// Lookup restricts what the lookup template function can read.
+	Lookup LookupConfig
+
+	// Limits bound the time and output of every render.
+	Limits render.Limits
+
+	// Cache keeps compiled templates between reconciles. Templates are compiled
+	// on every reconcile when it is nil.
+	Cache *render.Cache
+
+	// ForceConflicts makes the operator take over rendered fields of an Ingress
+	// that another field manager changed. Otherwise such conflicts fail the apply.
+	ForceConflicts bool
+
+	// IgnoreDifferences are fields left as they are in every Ingress once it
+	// exists, in addition to those of spec.ignoreDifferences.
+	IgnoreDifferences ingresstemplatev1alpha1.IgnoreDifferences
+
+	// ObserveOnly is used for IngressTemplates that do not set spec.observeOnly.
+	ObserveOnly bool
+
+	// timedOut holds the timeoutRetry of the IngressTemplates whose render
+	// timed out, by namespaced name.
+	timedOut sync.Map
+}
+
+// timeoutRetryBase and timeoutRetryMax bound the delay before a render that
+// timed out is retried. The delay doubles with every timeout in a row.
+const (
+	timeoutRetryBase = 10 * time.Second
+	timeoutRetryMax  = 10 * time.Minute
+)
+
+// timeoutRetry is when the render of an IngressTemplate that timed out is
+// retried.
+type timeoutRetry struct {
+	generation int64
+	timeouts   int
+	at         time.Time
 }
 
 //+kubebuilder:rbac:groups=ingress-template.takumakume.github.io,resources=ingresstemplates,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=ingress-template.takumakume.github.io,resources=ingresstemplates/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=ingress-template.takumakume.github.io,resources=ingresstemplates/finalizers,verbs=update
+//+kubebuilder:rbac:groups=ingress-template.takumakume.github.io,resources=ingresstemplatelibraries,verbs=get;list;watch
 //+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch
+//+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -61,6 +129,8 @@ func (r *IngressTemplateReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 	ingresstemplate := &ingresstemplatev1alpha1.IngressTemplate{}
 	if err := r.Get(ctx, req.NamespacedName, ingresstemplate); err != nil {
 		if apierrors.IsNotFound(err) {
+			r.forgetTemplates(req.NamespacedName.String())
+			forgetMetrics(req.NamespacedName)
 			return ctrl.Result{}, nil
 		}
 
@@ -72,15 +142,74 @@ func (r *IngressTemplateReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 	defer log.Info("finish reconcile loop")
 
 	if !ingresstemplate.GetDeletionTimestamp().IsZero() {
+		r.forgetTemplates(req.NamespacedName.String())
+		forgetMetrics(req.NamespacedName)
 		return ctrl.Result{}, nil
 	}
 
+	var retry timeoutRetry
+	if v, ok := r.timedOut.Load(req.NamespacedName.String()); ok && v.(timeoutRetry).generation == ingresstemplate.Generation {
+		retry = v.(timeoutRetry)
+		if wait := time.Until(retry.at); wait > 0 {
+			log.Info("skip render, it timed out recently", "retryAfter", wait)
+			return ctrl.Result{RequeueAfter: wait}, nil
+		}
+	}
+
 	log.Info("run create or update Ingress")
 
-	ingress, err := ingressTemplateToIngress(ingresstemplate)
+	opt, err := r.renderOptions(ctx, ingresstemplate)
+	lookup := r.newLookuper(ctx, ingresstemplate)
+	opt.Lookup = lookup
+
+	var ingress *networkingv1.Ingress
+	if err == nil {
+		ingress, err = ingressTemplateToIngress(ctx, ingresstemplate, opt)
+	}
+	redact := newRedactor(opt.Secrets)
 	if err != nil {
+		err = redact.Error(err)
+		log.Error(err, "unable to render Ingress")
+		cond := renderedCondition(err)
+		renderErr := renderError(err, redact)
+		r.Recorder.AnnotatedEventf(ingresstemplate, renderErrorAnnotations(renderErr), corev1.EventTypeWarning, cond.Reason, "%s", err.Error())
+
+		current, getErr := r.currentIngress(ctx, req.NamespacedName)
+		if getErr != nil {
+			return ctrl.Result{}, getErr
+		}
+		if statusUpdateErr := r.setStatus(ctx, ingresstemplate, observation{
+			rendered:  cond,
+			applied:   notAppliedCondition(),
+			renderErr: renderErr,
+			lookups:   lookup.refs,
+			ingress:   current,
+		}); statusUpdateErr != nil {
+			return ctrl.Result{}, statusUpdateErr
+		}
+		// A template that timed out is likely to time out again, keeping the
+		// operator busy on every retry, so it is retried after a delay that
+		// grows while it keeps timing out. The delay starts over when the
+		// IngressTemplate changes.
+		var timeoutErr *render.TimeoutError
+		if errors.As(err, &timeoutErr) {
+			retry.generation = ingresstemplate.Generation
+			retry.timeouts++
+			wait := timeoutRetryBase
+			for i := 1; i < retry.timeouts && wait < timeoutRetryMax; i++ {
+				wait *= 2
+			}
+			if wait > timeoutRetryMax {
+				wait = timeoutRetryMax
+			}
+			retry.at = time.Now().Add(wait)
+			r.timedOut.Store(req.NamespacedName.String(), retry)
+			return ctrl.Result{RequeueAfter: wait}, nil
+		}
 		return ctrl.Result{}, err
 	}
+	r.timedOut.Delete(req.NamespacedName.String())
+
 	ownerRef := metav1.NewControllerRef(
 		&ingress.ObjectMeta,
 		schema.GroupVersionKind{
@@ -92,85 +221,501 @@ func (r *IngressTemplateReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 	ownerRef.UID = ingresstemplate.GetUID()
 	ingress.ObjectMeta.SetOwnerReferences([]metav1.OwnerReference{*ownerRef})
 
-	createdIngress := &networkingv1.Ingress{}
-	if err := r.Get(ctx, req.NamespacedName, createdIngress); err != nil {
-		if apierrors.IsNotFound(err) {
-			log.Info("run create Ingress")
-			if createErr := r.Create(ctx, ingress); createErr != nil {
-				return ctrl.Result{}, createErr
-			}
-			log.Info("create ingress successful")
-
-			ingresstemplate.Status.Ready = corev1.ConditionTrue
-			if statusUpdateErr := r.Status().Update(ctx, ingresstemplate); statusUpdateErr != nil {
-				return ctrl.Result{}, statusUpdateErr
-			}
+	observeOnly := r.ObserveOnly
+	if ingresstemplate.Spec.ObserveOnly != nil {
+		observeOnly = *ingresstemplate.Spec.ObserveOnly
+	}
+	dryRun := ingresstemplate.Spec.DryRun
+	result, err := r.applyIngress(ctx, ingress, ingresstemplate.Spec.IgnoreDifferences, observeOnly || dryRun)
+	err = redact.Error(err)
+	applied := appliedCondition(err)
+	if err != nil {
+		log.Error(err, "unable to apply Ingress")
+		r.Recorder.Event(ingresstemplate, corev1.EventTypeWarning, applied.Reason, err.Error())
+	}
+	if result.drifted {
+		log.Info("corrected drift of Ingress")
+		driftCorrections.WithLabelValues(ingresstemplate.Namespace, ingresstemplate.Name).Inc()
+		r.Recorder.Event(ingresstemplate, corev1.EventTypeNormal, eventReasonDriftCorrected, "set back fields of the Ingress changed by someone else")
+	}
+	var drift *ingresstemplatev1alpha1.Drift
+	if err == nil && observeOnly {
+		drift = driftSummary(result.changes, redact)
+		applied = observedCondition(drift)
+		if drift.Count > 0 && !equality.Semantic.DeepEqual(drift, ingresstemplate.Status.Drift) {
+			r.Recorder.Event(ingresstemplate, corev1.EventTypeNormal, eventReasonDriftDetected, driftMessage(drift))
 		}
+	}
+	drifted := 0.0
+	if drift != nil && drift.Count > 0 {
+		drifted = 1
+	}
+	driftedTemplates.WithLabelValues(ingresstemplate.Namespace, ingresstemplate.Name).Set(drifted)
 
-		log.Error(err, "unable to fetch Ingress")
-		return ctrl.Result{}, err
-	} else {
-		needUpdateIngress := false
-		if !reflect.DeepEqual(createdIngress.ObjectMeta.Labels, ingress.ObjectMeta.Labels) {
-			log.Info(fmt.Sprintf("detects changes ObjectMeta.Label: %+v, %+v", createdIngress.ObjectMeta.Labels, ingress.ObjectMeta.Labels))
-			needUpdateIngress = true
+	var preview *ingresstemplatev1alpha1.Preview
+	if err == nil && dryRun {
+		preview, err = r.publishPreview(ctx, ingresstemplate, result, redact)
+		err = redact.Error(err)
+		applied = dryRunCondition(preview, err)
+		if err != nil {
+			log.Error(err, "unable to publish the rendered Ingress")
+			r.Recorder.Event(ingresstemplate, corev1.EventTypeWarning, applied.Reason, err.Error())
 		}
-		if !reflect.DeepEqual(createdIngress.ObjectMeta.Annotations, ingress.ObjectMeta.Annotations) {
-			log.Info(fmt.Sprintf("detects changes ObjectMeta.Annotations: %+v, %+v", createdIngress.ObjectMeta.Annotations, ingress.ObjectMeta.Annotations))
-			needUpdateIngress = true
-		}
-		if !reflect.DeepEqual(createdIngress.Spec, ingress.Spec) {
-			log.Info(fmt.Sprintf("detects changes Spec: %+v, %+v", createdIngress.Spec, ingress.Spec))
-			needUpdateIngress = true
+	}
+	if !dryRun && ingresstemplate.Status.Preview != nil {
+		if deleteErr := r.deletePreview(ctx, ingresstemplate); deleteErr != nil {
+			return ctrl.Result{}, deleteErr
 		}
+	}
 
-		if needUpdateIngress {
-			log.Info("run update Ingress")
-			if err := r.Update(ctx, ingress); err != nil {
-				return ctrl.Result{}, err
-			}
-			log.Info("update ingress successful")
+	if statusUpdateErr := r.setStatus(ctx, ingresstemplate, observation{
+		rendered: renderedCondition(nil),
+		applied:  applied,
+		lookups:  lookup.refs,
+		ingress:  result.ingress,
+		drift:    drift,
+		preview:  preview,
+	}); statusUpdateErr != nil {
+		return ctrl.Result{}, statusUpdateErr
+	}
+	return ctrl.Result{}, err
+}
+
+// currentIngress returns the Ingress named key, or nil when there is none.
+func (r *IngressTemplateReconciler) currentIngress(ctx context.Context, key types.NamespacedName) (*networkingv1.Ingress, error) {
+	current := &networkingv1.Ingress{}
+	if err := r.Get(ctx, key, current); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
 		}
+		return nil, err
 	}
+	return current, nil
+}
+
+// fieldManager is the field manager the operator applies Ingresses as.
+const fieldManager = "ingress-template-operator"
+
+// applyResult is the outcome of applyIngress.
+type applyResult struct {
+	// ingress is the Ingress in the cluster afterwards, or nil when there is none.
+	ingress *networkingv1.Ingress
+
+	// changes are the fields the apply changed, or would have changed when
+	// observing only.
+	changes []fieldChange
+
+	// drifted reports whether the apply set back fields someone else changed.
+	drifted bool
+
+	// obj is what was applied, or would have been applied when observing only,
+	// and hash is the hash of its rendered fields.
+	obj  *unstructured.Unstructured
+	hash string
+}
 
+// applyIngress applies ingress with server-side apply. The operator owns only
+// the fields it renders: fields set by other field managers are kept, and
+// fields it applied before but no longer renders are removed, including those
+// earlier versions of the operator wrote with updates. Fields ignored by
+// the operator or by ignore keep their values in the cluster once the Ingress
+// exists. With observeOnly, applyIngress only works out what an apply would
+// write and change. When the apply fails, the result holds the existing Ingress.
+func (r *IngressTemplateReconciler) applyIngress(ctx context.Context, ingress *networkingv1.Ingress, ignore *ingresstemplatev1alpha1.IgnoreDifferences, observeOnly bool) (applyResult, error) {
+	log := log.FromContext(ctx)
+
+	current, err := r.currentIngress(ctx, client.ObjectKeyFromObject(ingress))
 	if err != nil {
-		log.Error(err, "unable to create or update Ingress")
-		if statusUpdateErr := r.Update(ctx, ingresstemplate); statusUpdateErr != nil {
-			return ctrl.Result{}, statusUpdateErr
+		return applyResult{}, err
+	}
+	result := applyResult{ingress: current}
+	rules, err := newIgnoreRules(&r.IgnoreDifferences, ignore)
+	if err != nil {
+		return result, err
+	}
+	obj, err := ingressApplyConfiguration(ingress)
+	if err != nil {
+		return result, err
+	}
+	rendered := obj.DeepCopy()
+	rules.remove(rendered.Object)
+	hash, err := renderedHash(rendered.Object)
+	if err != nil {
+		return result, err
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[renderedHashAnnotation] = hash
+	obj.SetAnnotations(annotations)
+
+	var live map[string]interface{}
+	if current != nil {
+		if live, err = runtime.DefaultUnstructuredConverter.ToUnstructured(current); err != nil {
+			return result, err
 		}
-		return ctrl.Result{}, err
+		rules.preserve(obj.Object, live)
+	}
+	result.changes = ingressChanges(live, obj.Object)
+	result.obj = obj
+	result.hash = hash
+	if observeOnly {
+		log.Info("skip apply of Ingress", "changes", len(result.changes))
+		return result, nil
+	}
+	result.drifted = current != nil && current.Annotations[renderedHashAnnotation] == hash && len(result.changes) > 0
+	if current != nil {
+		if err := r.upgradeManagedFields(ctx, current); err != nil {
+			return result, err
+		}
+	}
+
+	opts := []client.PatchOption{client.FieldOwner(fieldManager)}
+	if r.ForceConflicts {
+		opts = append(opts, client.ForceOwnership)
 	}
 
-	return ctrl.Result{}, nil
+	log.Info("run apply Ingress")
+	if err := r.Patch(ctx, obj, client.Apply, opts...); err != nil {
+		return result, err
+	}
+	log.Info("apply Ingress successful")
+
+	result.ingress = &networkingv1.Ingress{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, result.ingress); err != nil {
+		return applyResult{}, err
+	}
+	return result, nil
 }
 
+// ingressApplyConfiguration returns ingress as the object of a server-side
+// apply, leaving out the fields the operator does not render.
+func ingressApplyConfiguration(ingress *networkingv1.Ingress) (*unstructured.Unstructured, error) {
+	ingress = ingress.DeepCopy()
+	ingress.SetGroupVersionKind(networkingv1.SchemeGroupVersion.WithKind("Ingress"))
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(ingress)
+	if err != nil {
+		return nil, err
+	}
+	obj := &unstructured.Unstructured{Object: content}
+	unstructured.RemoveNestedField(obj.Object, "metadata", "creationTimestamp")
+	unstructured.RemoveNestedField(obj.Object, "status")
+	return obj, nil
+}
+
+const (
+	configMapRefsIndex = ".spec.configMapRefs"
+	secretRefsIndex    = ".spec.secretRefs"
+	lookupsIndex       = ".status.lookups"
+	librariesIndex     = ".spec.libraries"
+)
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *IngressTemplateReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	if _, err := newIgnoreRules(&r.IgnoreDifferences); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &ingresstemplatev1alpha1.IngressTemplate{}, configMapRefsIndex, func(obj client.Object) []string {
+		return refNames(obj.(*ingresstemplatev1alpha1.IngressTemplate).Spec.ConfigMapRefs)
+	}); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &ingresstemplatev1alpha1.IngressTemplate{}, secretRefsIndex, func(obj client.Object) []string {
+		return refNames(obj.(*ingresstemplatev1alpha1.IngressTemplate).Spec.SecretRefs)
+	}); err != nil {
+		return err
+	}
+
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &ingresstemplatev1alpha1.IngressTemplate{}, lookupsIndex, func(obj client.Object) []string {
+		return lookupKeys(obj.(*ingresstemplatev1alpha1.IngressTemplate).Status.Lookups)
+	}); err != nil {
+		return err
+	}
+
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &ingresstemplatev1alpha1.IngressTemplate{}, librariesIndex, func(obj client.Object) []string {
+		return obj.(*ingresstemplatev1alpha1.IngressTemplate).Spec.Libraries
+	}); err != nil {
+		return err
+	}
+
+	b := ctrl.NewControllerManagedBy(mgr)
+	for _, gk := range r.Lookup.Kinds {
+		mapping, err := mgr.GetRESTMapper().RESTMapping(gk)
+		if err != nil {
+			return fmt.Errorf("lookup kind %s: %w", gk, err)
+		}
+		obj := &metav1.PartialObjectMetadata{}
+		obj.SetGroupVersionKind(mapping.GroupVersionKind)
+		b = b.Watches(
+			&source.Kind{Type: obj},
+			handler.EnqueueRequestsFromMapFunc(r.lookingUpIngressTemplates(gk)),
+		)
+	}
+
+	return b.
 		For(&ingresstemplatev1alpha1.IngressTemplate{}).
 		Owns(&networkingv1.Ingress{}).
+		Watches(
+			&source.Kind{Type: &corev1.Namespace{}},
+			handler.EnqueueRequestsFromMapFunc(r.namespaceToIngressTemplates),
+			builder.WithPredicates(predicate.Or(predicate.LabelChangedPredicate{}, predicate.AnnotationChangedPredicate{})),
+		).
+		Watches(
+			&source.Kind{Type: &corev1.ConfigMap{}},
+			handler.EnqueueRequestsFromMapFunc(r.referencingIngressTemplates(configMapRefsIndex)),
+		).
+		Watches(
+			&source.Kind{Type: &corev1.Secret{}},
+			handler.EnqueueRequestsFromMapFunc(r.referencingIngressTemplates(secretRefsIndex)),
+		).
+		Watches(
+			&source.Kind{Type: &ingresstemplatev1alpha1.IngressTemplateLibrary{}},
+			handler.EnqueueRequestsFromMapFunc(r.libraryToIngressTemplates),
+		).
 		Complete(r)
 }
 
-func ingressTemplateToIngress(ingresstemplate *ingresstemplatev1alpha1.IngressTemplate) (*networkingv1.Ingress, error) {
+func refNames(refs []corev1.LocalObjectReference) []string {
+	names := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		names = append(names, ref.Name)
+	}
+	return names
+}
+
+// referencingIngressTemplates returns a map function that enqueues the IngressTemplates
+// in the object's namespace whose index field refers to the object's name.
+func (r *IngressTemplateReconciler) referencingIngressTemplates(index string) handler.MapFunc {
+	return func(obj client.Object) []reconcile.Request {
+		list := &ingresstemplatev1alpha1.IngressTemplateList{}
+		if err := r.List(context.Background(), list, client.InNamespace(obj.GetNamespace()), client.MatchingFields{index: obj.GetName()}); err != nil {
+			log.Log.Error(err, "unable to list IngressTemplates", "index", index, "name", obj.GetName())
+			return nil
+		}
+		return requestsFor(list)
+	}
+}
+
+// lookingUpIngressTemplates returns a map function that enqueues the IngressTemplates
+// whose latest render looked up the object, which has the kind gk.
+func (r *IngressTemplateReconciler) lookingUpIngressTemplates(gk schema.GroupKind) handler.MapFunc {
+	return func(obj client.Object) []reconcile.Request {
+		list := &ingresstemplatev1alpha1.IngressTemplateList{}
+		key := lookupKey(gk, obj.GetNamespace(), obj.GetName())
+		if err := r.List(context.Background(), list, client.MatchingFields{lookupsIndex: key}); err != nil {
+			log.Log.Error(err, "unable to list IngressTemplates", "index", lookupsIndex, "key", key)
+			return nil
+		}
+		return requestsFor(list)
+	}
+}
+
+// libraryToIngressTemplates enqueues the IngressTemplates of any namespace that use the library.
+func (r *IngressTemplateReconciler) libraryToIngressTemplates(obj client.Object) []reconcile.Request {
+	list := &ingresstemplatev1alpha1.IngressTemplateList{}
+	if err := r.List(context.Background(), list, client.MatchingFields{librariesIndex: obj.GetName()}); err != nil {
+		log.Log.Error(err, "unable to list IngressTemplates", "IngressTemplateLibrary", obj.GetName())
+		return nil
+	}
+
+	return requestsFor(list)
+}
+
+// namespaceToIngressTemplates enqueues every IngressTemplate in the Namespace,
+// since templates can refer to the Namespace's labels and annotations.
+func (r *IngressTemplateReconciler) namespaceToIngressTemplates(obj client.Object) []reconcile.Request {
+	list := &ingresstemplatev1alpha1.IngressTemplateList{}
+	if err := r.List(context.Background(), list, client.InNamespace(obj.GetName())); err != nil {
+		log.Log.Error(err, "unable to list IngressTemplates", "Namespace", obj.GetName())
+		return nil
+	}
+
+	return requestsFor(list)
+}
+
+func requestsFor(list *ingresstemplatev1alpha1.IngressTemplateList) []reconcile.Request {
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for _, item := range list.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&item)})
+	}
+	return requests
+}
+
+// renderEngine returns the engine selected by spec.engine.
+func renderEngine(spec ingresstemplatev1alpha1.IngressTemplateSpec) (render.Engine, error) {
+	switch spec.Engine {
+	case ingresstemplatev1alpha1.EngineCEL:
+		if len(spec.Libraries) > 0 || spec.Delimiters != nil {
+			return nil, fmt.Errorf("spec.libraries and spec.delimiters can only be used with the %s engine", ingresstemplatev1alpha1.EngineGoTemplate)
+		}
+		return render.CELEngine{}, nil
+	case ingresstemplatev1alpha1.EngineGoTemplate, "":
+		return render.TemplateEngine{}, nil
+	}
+	return nil, fmt.Errorf("spec.engine %q is not supported", spec.Engine)
+}
+
+// forgetTemplates drops the compiled templates, and the timed out render, of a
+// deleted IngressTemplate.
+func (r *IngressTemplateReconciler) forgetTemplates(key string) {
+	r.timedOut.Delete(key)
+	if r.Cache != nil {
+		r.Cache.Delete(key)
+	}
+}
+
+// renderOptions returns the render options for ingresstemplate, applying the operator-wide
+// defaults and collecting the objects its templates can refer to.
+func (r *IngressTemplateReconciler) renderOptions(ctx context.Context, ingresstemplate *ingresstemplatev1alpha1.IngressTemplate) (render.Options, error) {
+	opt := render.Options{
+		Cluster:           r.Cluster,
+		StrictMissingKeys: r.StrictMissingKeys,
+		Limits:            r.Limits,
+		Cache:             r.Cache,
+		CacheKey:          client.ObjectKeyFromObject(ingresstemplate).String(),
+	}
+	if ingresstemplate.Spec.StrictMissingKeys != nil {
+		opt.StrictMissingKeys = *ingresstemplate.Spec.StrictMissingKeys
+	}
+
+	opt.Delimiters = renderDelimiters(ingresstemplate.Spec.Delimiters)
+
+	engine, err := renderEngine(ingresstemplate.Spec)
+	if err != nil {
+		return opt, err
+	}
+	opt.Engine = engine
+
+	values, err := parameters.Resolve(ingresstemplate.Spec.ParametersSchema, ingresstemplate.Spec.Parameters)
+	if err != nil {
+		return opt, err
+	}
+	opt.Values = values
+
+	ns := &corev1.Namespace{}
+	if err := r.Get(ctx, client.ObjectKey{Name: ingresstemplate.Namespace}, ns); err != nil {
+		return opt, err
+	}
+	opt.Namespace = render.Namespace{
+		Name:        ns.Name,
+		Labels:      ns.Labels,
+		Annotations: ns.Annotations,
+	}
+
+	for _, ref := range ingresstemplate.Spec.ConfigMapRefs {
+		cm := &corev1.ConfigMap{}
+		if err := r.Get(ctx, client.ObjectKey{Namespace: ingresstemplate.Namespace, Name: ref.Name}, cm); err != nil {
+			return opt, err
+		}
+		if opt.ConfigMaps == nil {
+			opt.ConfigMaps = map[string]map[string]string{}
+		}
+		opt.ConfigMaps[ref.Name] = cm.Data
+	}
+
+	for _, ref := range ingresstemplate.Spec.SecretRefs {
+		secret := &corev1.Secret{}
+		if err := r.Get(ctx, client.ObjectKey{Namespace: ingresstemplate.Namespace, Name: ref.Name}, secret); err != nil {
+			return opt, err
+		}
+		if opt.Secrets == nil {
+			opt.Secrets = map[string]map[string]string{}
+		}
+		data := make(map[string]string, len(secret.Data))
+		for k, v := range secret.Data {
+			data[k] = string(v)
+		}
+		opt.Secrets[ref.Name] = data
+	}
+
+	for _, name := range ingresstemplate.Spec.Libraries {
+		lib := &ingresstemplatev1alpha1.IngressTemplateLibrary{}
+		if err := r.Get(ctx, client.ObjectKey{Name: name}, lib); err != nil {
+			return opt, err
+		}
+		opt.Libraries = append(opt.Libraries, render.Library{
+			Name:       lib.Name,
+			Templates:  lib.Spec.Templates,
+			Delimiters: renderDelimiters(lib.Spec.Delimiters),
+		})
+	}
+
+	return opt, nil
+}
+
+func renderDelimiters(d *ingresstemplatev1alpha1.Delimiters) render.Delimiters {
+	if d == nil {
+		return render.Delimiters{}
+	}
+	return render.Delimiters{Left: d.Left, Right: d.Right}
+}
+
+func ingressTemplateToIngress(ctx context.Context, ingresstemplate *ingresstemplatev1alpha1.IngressTemplate, opt render.Options) (*networkingv1.Ingress, error) {
+	spec := ingresstemplate.Spec.DeepCopy()
 	generated := &networkingv1.Ingress{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        ingresstemplate.Name,
 			Namespace:   ingresstemplate.Namespace,
-			Annotations: ingresstemplate.Spec.IngressAnnotations,
-			Labels:      ingresstemplate.Spec.IngressLabels,
+			Annotations: spec.IngressAnnotations,
+			Labels:      spec.IngressLabels,
 		},
-		Spec: ingresstemplate.Spec.IngressSpecTemplate,
 	}
 
-	opt := render.Options{
-		Metadata: ingresstemplate.ObjectMeta,
+	opt.Metadata = ingresstemplate.ObjectMeta
+	opt.Spec = ingresstemplate.Spec
+
+	var err error
+	if spec.IngressSpecTemplateYAML != "" {
+		if !reflect.DeepEqual(spec.IngressSpecTemplate, ingresstemplatev1alpha1.IngressSpecTemplate{}) {
+			return nil, fmt.Errorf("spec.ingressSpecTemplate and spec.ingressSpecTemplateYAML are mutually exclusive")
+		}
+		generated, err = render.RenderYAML(ctx, generated, spec.IngressSpecTemplateYAML, opt)
+		if err != nil {
+			return nil, withIngressTemplatePath(err, "spec.ingressSpecTemplateYAML")
+		}
+	} else {
+		generated, err = render.RenderSpec(ctx, generated, &spec.IngressSpecTemplate, opt)
+		if err != nil {
+			return nil, withIngressTemplatePath(err, "spec.ingressSpecTemplate")
+		}
+		if err := expandAliases(&generated.Spec, &spec.IngressSpecTemplate); err != nil {
+			return nil, err
+		}
 	}
 
-	generated, err := render.Render(generated, opt)
-	if err != nil {
-		return nil, err
+	if spec.AutoTLS != nil {
+		if err := autoTLS(ctx, &generated.Spec, spec.AutoTLS, opt); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(spec.Patches) > 0 {
+		if generated, err = applyPatches(ctx, generated, spec.Patches, opt); err != nil {
+			return nil, err
+		}
 	}
 
 	return generated, nil
 }
+
+// withIngressTemplatePath rewrites the path of the render error err, which is
+// relative to the rendered Ingress, into the path of the IngressTemplate field
+// the user wrote. The spec of the Ingress comes from specPath.
+func withIngressTemplatePath(err error, specPath string) error {
+	var fieldErr *render.FieldError
+	if !errors.As(err, &fieldErr) {
+		return err
+	}
+	for _, p := range [][2]string{
+		{"metadata.labels", "spec.ingressLabels"},
+		{"metadata.annotations", "spec.ingressAnnotations"},
+		{"spec", specPath},
+	} {
+		if rest := strings.TrimPrefix(fieldErr.Path, p[0]); rest != fieldErr.Path && (rest == "" || rest[0] == '.' || rest[0] == '[') {
+			fieldErr.Path = p[1] + rest
+			break
+		}
+	}
+	return err
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package controllers
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"testing"
@@ -28,14 +29,19 @@ import (
 
 	v1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	ingresstemplatev1alpha1 "github.com/takumakume/ingress-template-operator/api/v1alpha1"
+	"github.com/takumakume/ingress-template-operator/pkg/render"
 )
 
 func Test_ingressTemplateToIngress(t *testing.T) {
 	type args struct {
 		ingresstemplate *ingresstemplatev1alpha1.IngressTemplate
+		opt             render.Options
 	}
 	tests := []struct {
 		name    string
@@ -58,15 +64,17 @@ func Test_ingressTemplateToIngress(t *testing.T) {
 						IngressLabels: map[string]string{
 							"key2": "value2-{{ .Metadata.Namespace }}",
 						},
-						IngressSpecTemplate: networkingv1.IngressSpec{
-							TLS: []networkingv1.IngressTLS{
+						IngressSpecTemplate: ingresstemplatev1alpha1.IngressSpecTemplate{
+							TLS: []ingresstemplatev1alpha1.IngressTLSTemplate{
 								{
-									Hosts: []string{
-										"{{ .Metadata.Namespace }}.example.com",
+									IngressTLS: networkingv1.IngressTLS{
+										Hosts: []string{
+											"{{ .Metadata.Namespace }}.example.com",
+										},
 									},
 								},
 							},
-							Rules: []networkingv1.IngressRule{
+							Rules: []ingresstemplatev1alpha1.IngressRuleTemplate{
 								{
 									Host: "{{ .Metadata.Namespace }}.example.com",
 								},
@@ -102,10 +110,475 @@ func Test_ingressTemplateToIngress(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "context",
+			args: args{
+				ingresstemplate: &ingresstemplatev1alpha1.IngressTemplate{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test",
+						Namespace: "ns",
+						Labels: map[string]string{
+							"app": "hoge",
+						},
+					},
+					Spec: ingresstemplatev1alpha1.IngressTemplateSpec{
+						IngressLabels: map[string]string{
+							"app": "{{ .Metadata.Labels.app }}",
+							"env": "{{ .Namespace.Labels.env }}",
+						},
+						IngressAnnotations: map[string]string{
+							"cluster": "{{ .Cluster.Name }}",
+							"labels":  "{{ .Spec.IngressLabels.app }}",
+						},
+						IngressSpecTemplate: ingresstemplatev1alpha1.IngressSpecTemplate{
+							Rules: []ingresstemplatev1alpha1.IngressRuleTemplate{
+								{
+									Host: "{{ .Metadata.Labels.app }}-{{ .Namespace.Name }}.{{ .Cluster.BaseDomain }}",
+								},
+							},
+						},
+					},
+				},
+				opt: render.Options{
+					Namespace: render.Namespace{
+						Name: "ns",
+						Labels: map[string]string{
+							"env": "dev",
+						},
+					},
+					Cluster: render.Cluster{
+						Name:       "cluster1",
+						BaseDomain: "example.com",
+					},
+				},
+			},
+			want: &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "ns",
+					Labels: map[string]string{
+						"app": "hoge",
+						"env": "dev",
+					},
+					Annotations: map[string]string{
+						"cluster": "cluster1",
+						"labels":  "{{ .Metadata.Labels.app }}",
+					},
+				},
+				Spec: networkingv1.IngressSpec{
+					Rules: []networkingv1.IngressRule{
+						{
+							Host: "hoge-ns.example.com",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "configmaps and secrets",
+			args: args{
+				ingresstemplate: &ingresstemplatev1alpha1.IngressTemplate{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test",
+						Namespace: "ns",
+					},
+					Spec: ingresstemplatev1alpha1.IngressTemplateSpec{
+						IngressAnnotations: map[string]string{
+							"cert-manager.io/cluster-issuer":              "{{ .ConfigMaps.env.issuer }}",
+							"nginx.ingress.kubernetes.io/auth-signin-key": "{{ .Secrets.auth.key }}",
+						},
+						IngressSpecTemplate: ingresstemplatev1alpha1.IngressSpecTemplate{
+							Rules: []ingresstemplatev1alpha1.IngressRuleTemplate{
+								{
+									Host: "www.{{ .ConfigMaps.env.domain }}",
+								},
+							},
+						},
+					},
+				},
+				opt: render.Options{
+					ConfigMaps: map[string]map[string]string{
+						"env": {
+							"domain": "example.com",
+							"issuer": "letsencrypt",
+						},
+					},
+					Secrets: map[string]map[string]string{
+						"auth": {
+							"key": "s3cr3t",
+						},
+					},
+				},
+			},
+			want: &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "ns",
+					Annotations: map[string]string{
+						"cert-manager.io/cluster-issuer":              "letsencrypt",
+						"nginx.ingress.kubernetes.io/auth-signin-key": "s3cr3t",
+					},
+				},
+				Spec: networkingv1.IngressSpec{
+					Rules: []networkingv1.IngressRule{
+						{
+							Host: "www.example.com",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "parameters",
+			args: args{
+				ingresstemplate: &ingresstemplatev1alpha1.IngressTemplate{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test",
+						Namespace: "ns",
+					},
+					Spec: ingresstemplatev1alpha1.IngressTemplateSpec{
+						IngressSpecTemplate: ingresstemplatev1alpha1.IngressSpecTemplate{
+							Rules: []ingresstemplatev1alpha1.IngressRuleTemplate{
+								{
+									Host: "{{ .Values.service }}.example.com",
+								},
+							},
+						},
+					},
+				},
+				opt: render.Options{
+					Values: map[string]interface{}{
+						"service": "app",
+					},
+				},
+			},
+			want: &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "ns",
+				},
+				Spec: networkingv1.IngressSpec{
+					Rules: []networkingv1.IngressRule{
+						{
+							Host: "app.example.com",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "libraries",
+			args: args{
+				ingresstemplate: &ingresstemplatev1alpha1.IngressTemplate{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test",
+						Namespace: "ns",
+					},
+					Spec: ingresstemplatev1alpha1.IngressTemplateSpec{
+						Libraries: []string{"common"},
+						IngressSpecTemplate: ingresstemplatev1alpha1.IngressSpecTemplate{
+							Rules: []ingresstemplatev1alpha1.IngressRuleTemplate{
+								{
+									Host: `{{ include "host" . }}`,
+								},
+							},
+						},
+					},
+				},
+				opt: render.Options{
+					Libraries: []render.Library{
+						{
+							Name:      "common",
+							Templates: `{{ define "host" }}{{ .Metadata.Name }}.{{ .Metadata.Namespace }}.example.com{{ end }}`,
+						},
+					},
+				},
+			},
+			want: &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "ns",
+				},
+				Spec: networkingv1.IngressSpec{
+					Rules: []networkingv1.IngressRule{
+						{
+							Host: "test.ns.example.com",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "delimiters",
+			args: args{
+				ingresstemplate: &ingresstemplatev1alpha1.IngressTemplate{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test",
+						Namespace: "ns",
+					},
+					Spec: ingresstemplatev1alpha1.IngressTemplateSpec{
+						IngressSpecTemplate: ingresstemplatev1alpha1.IngressSpecTemplate{
+							Rules: []ingresstemplatev1alpha1.IngressRuleTemplate{
+								{
+									Host: "[[ .Metadata.Name ]].[[ .Metadata.Namespace ]].example.com",
+								},
+							},
+						},
+					},
+				},
+				opt: render.Options{
+					Delimiters: render.Delimiters{Left: "[[", Right: "]]"},
+				},
+			},
+			want: &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "ns",
+				},
+				Spec: networkingv1.IngressSpec{
+					Rules: []networkingv1.IngressRule{
+						{
+							Host: "test.ns.example.com",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "missing key",
+			args: args{
+				ingresstemplate: &ingresstemplatev1alpha1.IngressTemplate{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test",
+						Namespace: "ns",
+					},
+					Spec: ingresstemplatev1alpha1.IngressTemplateSpec{
+						IngressLabels: map[string]string{
+							"key": "{{ .Metadata.Labels.missing }}",
+						},
+					},
+				},
+			},
+			want: &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "ns",
+					Labels: map[string]string{
+						"key": "<no value>",
+					},
+				},
+			},
+		},
+		{
+			name: "missing key with strict",
+			args: args{
+				ingresstemplate: &ingresstemplatev1alpha1.IngressTemplate{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test",
+						Namespace: "ns",
+					},
+					Spec: ingresstemplatev1alpha1.IngressTemplateSpec{
+						IngressLabels: map[string]string{
+							"key": "{{ .Metadata.Labels.missing }}",
+						},
+					},
+				},
+				opt: render.Options{
+					StrictMissingKeys: true,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "yaml template",
+			args: args{
+				ingresstemplate: &ingresstemplatev1alpha1.IngressTemplate{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test",
+						Namespace: "ns",
+					},
+					Spec: ingresstemplatev1alpha1.IngressTemplateSpec{
+						IngressSpecTemplateYAML: `
+rules:
+{{- range list "www" "api" }}
+- host: {{ . }}-{{ $.Metadata.Namespace }}.example.com
+{{- end }}
+`,
+					},
+				},
+			},
+			want: &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "ns",
+				},
+				Spec: networkingv1.IngressSpec{
+					Rules: []networkingv1.IngressRule{
+						{
+							Host: "www-ns.example.com",
+						},
+						{
+							Host: "api-ns.example.com",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "cel",
+			args: args{
+				ingresstemplate: &ingresstemplatev1alpha1.IngressTemplate{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test",
+						Namespace: "ns",
+					},
+					Spec: ingresstemplatev1alpha1.IngressTemplateSpec{
+						IngressLabels: map[string]string{
+							"app": "${ Metadata.Name }",
+						},
+						IngressSpecTemplateYAML: `rules: ${ ["www", "api"].map(h, {"host": h + "-" + Metadata.Namespace + ".example.com"}) }`,
+					},
+				},
+				opt: render.Options{
+					Engine: render.CELEngine{},
+				},
+			},
+			want: &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "ns",
+					Labels: map[string]string{
+						"app": "test",
+					},
+				},
+				Spec: networkingv1.IngressSpec{
+					Rules: []networkingv1.IngressRule{
+						{
+							Host: "www-ns.example.com",
+						},
+						{
+							Host: "api-ns.example.com",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "when",
+			args: args{
+				ingresstemplate: &ingresstemplatev1alpha1.IngressTemplate{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test",
+						Namespace: "ns",
+					},
+					Spec: ingresstemplatev1alpha1.IngressTemplateSpec{
+						IngressSpecTemplate: ingresstemplatev1alpha1.IngressSpecTemplate{
+							Rules: []ingresstemplatev1alpha1.IngressRuleTemplate{
+								{
+									When: `${ Metadata.Namespace == "prod" }`,
+									Host: "www.example.com",
+								},
+								{
+									When: `${ Metadata.Namespace != "prod" }`,
+									Host: "${ Metadata.Namespace }.example.com",
+								},
+							},
+						},
+					},
+				},
+				opt: render.Options{
+					Engine: render.CELEngine{},
+				},
+			},
+			want: &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "ns",
+				},
+				Spec: networkingv1.IngressSpec{
+					Rules: []networkingv1.IngressRule{
+						{
+							Host: "ns.example.com",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "aliases",
+			args: args{
+				ingresstemplate: &ingresstemplatev1alpha1.IngressTemplate{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test",
+						Namespace: "ns",
+					},
+					Spec: ingresstemplatev1alpha1.IngressTemplateSpec{
+						IngressSpecTemplate: ingresstemplatev1alpha1.IngressSpecTemplate{
+							TLS: []ingresstemplatev1alpha1.IngressTLSTemplate{
+								{
+									IngressTLS: networkingv1.IngressTLS{
+										Hosts: []string{"{{ .Metadata.Namespace }}.example.com"},
+									},
+								},
+							},
+							Rules: []ingresstemplatev1alpha1.IngressRuleTemplate{
+								{
+									Host:    "{{ .Metadata.Namespace }}.example.com",
+									Aliases: []string{"www.{{ .Metadata.Namespace }}.example.com"},
+								},
+							},
+						},
+					},
+				},
+			},
+			want: &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test",
+					Namespace: "ns",
+				},
+				Spec: networkingv1.IngressSpec{
+					TLS: []networkingv1.IngressTLS{
+						{
+							Hosts: []string{"ns.example.com", "www.ns.example.com"},
+						},
+					},
+					Rules: []networkingv1.IngressRule{
+						{
+							Host: "ns.example.com",
+						},
+						{
+							Host: "www.ns.example.com",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "both ingressSpecTemplate and yaml template",
+			args: args{
+				ingresstemplate: &ingresstemplatev1alpha1.IngressTemplate{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test",
+						Namespace: "ns",
+					},
+					Spec: ingresstemplatev1alpha1.IngressTemplateSpec{
+						IngressSpecTemplate: ingresstemplatev1alpha1.IngressSpecTemplate{
+							Rules: []ingresstemplatev1alpha1.IngressRuleTemplate{
+								{
+									Host: "www.example.com",
+								},
+							},
+						},
+						IngressSpecTemplateYAML: `rules: []`,
+					},
+				},
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := ingressTemplateToIngress(tt.args.ingresstemplate)
+			got, err := ingressTemplateToIngress(context.Background(), tt.args.ingresstemplate, tt.args.opt)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ingressTemplateToIngress() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -117,6 +590,65 @@ func Test_ingressTemplateToIngress(t *testing.T) {
 	}
 }
 
+func Test_ingressTemplateToIngress_errorPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     ingresstemplatev1alpha1.IngressTemplateSpec
+		wantPath string
+	}{
+		{
+			name: "spec",
+			spec: ingresstemplatev1alpha1.IngressTemplateSpec{
+				IngressSpecTemplate: ingresstemplatev1alpha1.IngressSpecTemplate{
+					Rules: []ingresstemplatev1alpha1.IngressRuleTemplate{{Host: "www"}, {Host: "{{ .Values.missing }}"}},
+				},
+			},
+			wantPath: "spec.ingressSpecTemplate.rules[1].host",
+		},
+		{
+			name: "yaml",
+			spec: ingresstemplatev1alpha1.IngressTemplateSpec{
+				IngressSpecTemplateYAML: "rules:\n- host: {{ .Values.missing }}",
+			},
+			wantPath: "spec.ingressSpecTemplateYAML",
+		},
+		{
+			name: "labels",
+			spec: ingresstemplatev1alpha1.IngressTemplateSpec{
+				IngressLabels: map[string]string{"app": "{{ .Values.missing }}"},
+			},
+			wantPath: "spec.ingressLabels[app]",
+		},
+		{
+			name: "annotation keys",
+			spec: ingresstemplatev1alpha1.IngressTemplateSpec{
+				IngressAnnotations: map[string]string{"{{ .Values.missing }}/owner": "team"},
+			},
+			wantPath: "spec.ingressAnnotations[{{ .Values.missing }}/owner]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ingresstemplate := &ingresstemplatev1alpha1.IngressTemplate{
+				ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "ns"},
+				Spec:       tt.spec,
+			}
+			_, err := ingressTemplateToIngress(context.Background(), ingresstemplate, render.Options{StrictMissingKeys: true})
+
+			renderErr := renderError(err, newRedactor())
+			if renderErr == nil {
+				t.Fatalf("ingressTemplateToIngress() error = %v, want a field error", err)
+			}
+			if renderErr.Path != tt.wantPath {
+				t.Errorf("ingressTemplateToIngress() error path = %v, want %v", renderErr.Path, tt.wantPath)
+			}
+			if renderErr.Line == 0 || renderErr.Snippet == "" {
+				t.Errorf("ingressTemplateToIngress() error = %+v, want its line and snippet", renderErr)
+			}
+		})
+	}
+}
+
 var _ = Describe("IngressTemplate controller", func() {
 	BeforeEach(func() {
 		err := k8sClient.DeleteAllOf(ctx, &ingresstemplatev1alpha1.IngressTemplate{}, client.InNamespace("test"))
@@ -139,15 +671,17 @@ var _ = Describe("IngressTemplate controller", func() {
 				IngressLabels: map[string]string{
 					"key2": "value2-{{ .Metadata.Namespace }}",
 				},
-				IngressSpecTemplate: networkingv1.IngressSpec{
-					TLS: []networkingv1.IngressTLS{
+				IngressSpecTemplate: ingresstemplatev1alpha1.IngressSpecTemplate{
+					TLS: []ingresstemplatev1alpha1.IngressTLSTemplate{
 						{
-							Hosts: []string{
-								"{{ .Metadata.Namespace }}.example.com",
+							IngressTLS: networkingv1.IngressTLS{
+								Hosts: []string{
+									"{{ .Metadata.Namespace }}.example.com",
+								},
 							},
 						},
 					},
-					Rules: []networkingv1.IngressRule{
+					Rules: []ingresstemplatev1alpha1.IngressRuleTemplate{
 						{
 							Host: "{{ .Metadata.Namespace }}.example.com",
 						},
@@ -167,6 +701,14 @@ var _ = Describe("IngressTemplate controller", func() {
 			return o.Status.Ready, nil
 		}, 20, 1).Should(Equal(v1.ConditionTrue))
 
+		o := &ingresstemplatev1alpha1.IngressTemplate{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "test", Name: "sample"}, o)).Should(Succeed())
+		Expect(meta.IsStatusConditionTrue(o.Status.Conditions, ingresstemplatev1alpha1.ConditionTypeReady)).Should(BeTrue())
+		Expect(meta.IsStatusConditionFalse(o.Status.Conditions, ingresstemplatev1alpha1.ConditionTypeDegraded)).Should(BeTrue())
+		Expect(o.Status.ObservedGeneration).Should(Equal(o.Generation))
+		Expect(o.Status.IngressRef).ShouldNot(BeNil())
+		Expect(o.Status.IngressRef.Name).Should(Equal("sample"))
+
 		created := &networkingv1.Ingress{}
 		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "test", Name: "sample"}, created)).Should(Succeed())
 		Expect(created.ObjectMeta.Name).Should(Equal("sample"))
@@ -264,4 +806,363 @@ var _ = Describe("IngressTemplate controller", func() {
 			return nil
 		}, 20, 1).Should(Succeed())
 	})
+
+	It("reports render errors on the status", func() {
+		strict := true
+		ingresstemplate := &ingresstemplatev1alpha1.IngressTemplate{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "strict",
+				Namespace: "test",
+			},
+			Spec: ingresstemplatev1alpha1.IngressTemplateSpec{
+				StrictMissingKeys: &strict,
+				IngressSpecTemplate: ingresstemplatev1alpha1.IngressSpecTemplate{
+					Rules: []ingresstemplatev1alpha1.IngressRuleTemplate{
+						{
+							Host: "{{ .Metadata.Labels.missing }}.example.com",
+						},
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, ingresstemplate)).Should(Succeed())
+
+		Eventually(func() (metav1.ConditionStatus, error) {
+			o := &ingresstemplatev1alpha1.IngressTemplate{}
+			if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "test", Name: "strict"}, o); err != nil {
+				return "", err
+			}
+			cond := meta.FindStatusCondition(o.Status.Conditions, ingresstemplatev1alpha1.ConditionTypeRendered)
+			if cond == nil {
+				return "", fmt.Errorf("condition %s is not set", ingresstemplatev1alpha1.ConditionTypeRendered)
+			}
+			return cond.Status, nil
+		}, 20, 1).Should(Equal(metav1.ConditionFalse))
+
+		o := &ingresstemplatev1alpha1.IngressTemplate{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "test", Name: "strict"}, o)).Should(Succeed())
+		Expect(o.Status.RenderError).ShouldNot(BeNil())
+		Expect(o.Status.RenderError.Path).Should(Equal("spec.ingressSpecTemplate.rules[0].host"))
+		Expect(o.Status.RenderError.Line).Should(Equal(1))
+		Expect(o.Status.RenderError.Snippet).Should(Equal("{{ .Metadata.Labels.missing }}.example.com"))
+
+		Consistently(func() bool {
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "test", Name: "strict"}, &networkingv1.Ingress{})
+			return apierrors.IsNotFound(err)
+		}, 3, 1).Should(BeTrue())
+	})
+
+	It("reports invalid parameters before rendering", func() {
+		ingresstemplate := &ingresstemplatev1alpha1.IngressTemplate{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "parameters",
+				Namespace: "test",
+			},
+			Spec: ingresstemplatev1alpha1.IngressTemplateSpec{
+				ParametersSchema: &apiextensionsv1.JSON{Raw: []byte(`{"type":"object","required":["service"],"properties":{"service":{"type":"string"}}}`)},
+				IngressSpecTemplate: ingresstemplatev1alpha1.IngressSpecTemplate{
+					Rules: []ingresstemplatev1alpha1.IngressRuleTemplate{
+						{
+							Host: "{{ .Values.service }}.example.com",
+						},
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, ingresstemplate)).Should(Succeed())
+
+		Eventually(func() (string, error) {
+			o := &ingresstemplatev1alpha1.IngressTemplate{}
+			if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "test", Name: "parameters"}, o); err != nil {
+				return "", err
+			}
+			cond := meta.FindStatusCondition(o.Status.Conditions, ingresstemplatev1alpha1.ConditionTypeRendered)
+			if cond == nil {
+				return "", fmt.Errorf("condition %s is not set", ingresstemplatev1alpha1.ConditionTypeRendered)
+			}
+			return cond.Reason, nil
+		}, 20, 1).Should(Equal(ingresstemplatev1alpha1.ReasonInvalidParameters))
+	})
+
+	It("re-renders when the labels of the Namespace change", func() {
+		ingresstemplate := &ingresstemplatev1alpha1.IngressTemplate{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "namespace",
+				Namespace: "test",
+			},
+			Spec: ingresstemplatev1alpha1.IngressTemplateSpec{
+				IngressLabels: map[string]string{
+					"env": "{{ .Namespace.Labels.env }}",
+				},
+				IngressSpecTemplate: ingresstemplatev1alpha1.IngressSpecTemplate{
+					Rules: []ingresstemplatev1alpha1.IngressRuleTemplate{
+						{
+							Host: "{{ .Namespace.Name }}.example.com",
+						},
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, ingresstemplate)).Should(Succeed())
+
+		ns := &v1.Namespace{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Name: "test"}, ns)).Should(Succeed())
+		if ns.Labels == nil {
+			ns.Labels = map[string]string{}
+		}
+		ns.Labels["env"] = "staging"
+		Expect(k8sClient.Update(ctx, ns)).Should(Succeed())
+
+		Eventually(func() (string, error) {
+			o := &networkingv1.Ingress{}
+			if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "test", Name: "namespace"}, o); err != nil {
+				return "", err
+			}
+			return o.ObjectMeta.Labels["env"], nil
+		}, 20, 1).Should(Equal("staging"))
+	})
+
+	It("re-renders when a referenced ConfigMap changes", func() {
+		cm := &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "inputs",
+				Namespace: "test",
+			},
+			Data: map[string]string{
+				"domain": "example.com",
+			},
+		}
+		Expect(k8sClient.Create(ctx, cm)).Should(Succeed())
+
+		ingresstemplate := &ingresstemplatev1alpha1.IngressTemplate{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "inputs",
+				Namespace: "test",
+			},
+			Spec: ingresstemplatev1alpha1.IngressTemplateSpec{
+				ConfigMapRefs: []v1.LocalObjectReference{
+					{Name: "inputs"},
+				},
+				IngressSpecTemplate: ingresstemplatev1alpha1.IngressSpecTemplate{
+					Rules: []ingresstemplatev1alpha1.IngressRuleTemplate{
+						{
+							Host: "www.{{ .ConfigMaps.inputs.domain }}",
+						},
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, ingresstemplate)).Should(Succeed())
+
+		Eventually(func() (string, error) {
+			o := &networkingv1.Ingress{}
+			if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "test", Name: "inputs"}, o); err != nil {
+				return "", err
+			}
+			return o.Spec.Rules[0].Host, nil
+		}, 20, 1).Should(Equal("www.example.com"))
+
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "test", Name: "inputs"}, cm)).Should(Succeed())
+		cm.Data["domain"] = "example.org"
+		Expect(k8sClient.Update(ctx, cm)).Should(Succeed())
+
+		Eventually(func() (string, error) {
+			o := &networkingv1.Ingress{}
+			if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "test", Name: "inputs"}, o); err != nil {
+				return "", err
+			}
+			return o.Spec.Rules[0].Host, nil
+		}, 20, 1).Should(Equal("www.example.org"))
+	})
+
+	It("re-renders when a looked up object is created", func() {
+		ingresstemplate := &ingresstemplatev1alpha1.IngressTemplate{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "lookup",
+				Namespace: "test",
+			},
+			Spec: ingresstemplatev1alpha1.IngressTemplateSpec{
+				IngressAnnotations: map[string]string{
+					"backend-port": `{{ with lookup "v1" "Service" "" "backend" }}{{ (index .spec.ports 0).name }}{{ else }}none{{ end }}`,
+				},
+				IngressSpecTemplate: ingresstemplatev1alpha1.IngressSpecTemplate{
+					Rules: []ingresstemplatev1alpha1.IngressRuleTemplate{
+						{
+							Host: "lookup.example.com",
+						},
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, ingresstemplate)).Should(Succeed())
+
+		Eventually(func() (string, error) {
+			o := &networkingv1.Ingress{}
+			if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "test", Name: "lookup"}, o); err != nil {
+				return "", err
+			}
+			return o.Annotations["backend-port"], nil
+		}, 20, 1).Should(Equal("none"))
+
+		svc := &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "backend",
+				Namespace: "test",
+			},
+			Spec: v1.ServiceSpec{
+				Ports: []v1.ServicePort{
+					{Name: "http", Port: 80},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, svc)).Should(Succeed())
+
+		Eventually(func() (string, error) {
+			o := &networkingv1.Ingress{}
+			if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "test", Name: "lookup"}, o); err != nil {
+				return "", err
+			}
+			return o.Annotations["backend-port"], nil
+		}, 20, 1).Should(Equal("http"))
+	})
+
+	It("re-renders when a library changes", func() {
+		lib := &ingresstemplatev1alpha1.IngressTemplateLibrary{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "hosts",
+			},
+			Spec: ingresstemplatev1alpha1.IngressTemplateLibrarySpec{
+				Templates: `{{ define "host" }}{{ .Metadata.Name }}.example.com{{ end }}`,
+			},
+		}
+		Expect(k8sClient.Create(ctx, lib)).Should(Succeed())
+
+		ingresstemplate := &ingresstemplatev1alpha1.IngressTemplate{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "library",
+				Namespace: "test",
+			},
+			Spec: ingresstemplatev1alpha1.IngressTemplateSpec{
+				Libraries: []string{"hosts"},
+				IngressSpecTemplate: ingresstemplatev1alpha1.IngressSpecTemplate{
+					Rules: []ingresstemplatev1alpha1.IngressRuleTemplate{
+						{
+							Host: `{{ template "host" . }}`,
+						},
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, ingresstemplate)).Should(Succeed())
+
+		Eventually(func() (string, error) {
+			o := &networkingv1.Ingress{}
+			if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "test", Name: "library"}, o); err != nil {
+				return "", err
+			}
+			return o.Spec.Rules[0].Host, nil
+		}, 20, 1).Should(Equal("library.example.com"))
+
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Name: "hosts"}, lib)).Should(Succeed())
+		lib.Spec.Templates = `{{ define "host" }}{{ .Metadata.Name }}.example.org{{ end }}`
+		Expect(k8sClient.Update(ctx, lib)).Should(Succeed())
+
+		Eventually(func() (string, error) {
+			o := &networkingv1.Ingress{}
+			if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "test", Name: "library"}, o); err != nil {
+				return "", err
+			}
+			return o.Spec.Rules[0].Host, nil
+		}, 20, 1).Should(Equal("library.example.org"))
+	})
+
+	It("keeps the fields of other managers and removes the ones no longer rendered", func() {
+		ingresstemplate := &ingresstemplatev1alpha1.IngressTemplate{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "managers",
+				Namespace: "test",
+			},
+			Spec: ingresstemplatev1alpha1.IngressTemplateSpec{
+				IngressAnnotations: map[string]string{
+					"rendered": "{{ .Metadata.Name }}",
+					"dropped":  "{{ .Metadata.Name }}",
+				},
+				IngressSpecTemplate: ingresstemplatev1alpha1.IngressSpecTemplate{
+					Rules: []ingresstemplatev1alpha1.IngressRuleTemplate{
+						{
+							Host: "{{ .Metadata.Name }}.example.com",
+						},
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, ingresstemplate)).Should(Succeed())
+
+		o := &networkingv1.Ingress{}
+		Eventually(func() (map[string]string, error) {
+			if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "test", Name: "managers"}, o); err != nil {
+				return nil, err
+			}
+			return o.Annotations, nil
+		}, 20, 1).Should(HaveKeyWithValue("dropped", "managers"))
+
+		patch := client.MergeFrom(o.DeepCopy())
+		o.Annotations["other"] = "kept"
+		Expect(k8sClient.Patch(ctx, o, patch, client.FieldOwner("other-manager"))).Should(Succeed())
+
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Namespace: "test", Name: "managers"}, ingresstemplate)).Should(Succeed())
+		delete(ingresstemplate.Spec.IngressAnnotations, "dropped")
+		ingresstemplate.Spec.IngressSpecTemplate.Rules[0].Host = "{{ .Metadata.Name }}.example.org"
+		Expect(k8sClient.Update(ctx, ingresstemplate)).Should(Succeed())
+
+		Eventually(func() (string, error) {
+			if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "test", Name: "managers"}, o); err != nil {
+				return "", err
+			}
+			return o.Spec.Rules[0].Host, nil
+		}, 20, 1).Should(Equal("managers.example.org"))
+		Expect(o.Annotations).Should(HaveKeyWithValue("other", "kept"))
+		Expect(o.Annotations).Should(HaveKeyWithValue("rendered", "managers"))
+		Expect(o.Annotations).ShouldNot(HaveKey("dropped"))
+	})
+
+	It("removes the fields earlier versions wrote once they are no longer rendered", func() {
+		legacy := &networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "legacy",
+				Namespace:   "test",
+				Annotations: map[string]string{"legacy": "true"},
+			},
+			Spec: networkingv1.IngressSpec{
+				Rules: []networkingv1.IngressRule{{Host: "old.example.com"}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, legacy, client.FieldOwner(legacyFieldManager))).Should(Succeed())
+
+		ingresstemplate := &ingresstemplatev1alpha1.IngressTemplate{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "legacy",
+				Namespace: "test",
+			},
+			Spec: ingresstemplatev1alpha1.IngressTemplateSpec{
+				IngressSpecTemplate: ingresstemplatev1alpha1.IngressSpecTemplate{
+					Rules: []ingresstemplatev1alpha1.IngressRuleTemplate{
+						{
+							Host: "{{ .Metadata.Name }}.example.com",
+						},
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, ingresstemplate)).Should(Succeed())
+
+		o := &networkingv1.Ingress{}
+		Eventually(func() (string, error) {
+			if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "test", Name: "legacy"}, o); err != nil {
+				return "", err
+			}
+			return o.Spec.Rules[0].Host, nil
+		}, 20, 1).Should(Equal("legacy.example.com"))
+		Expect(o.Annotations).ShouldNot(HaveKey("legacy"))
+	})
 })
@@ -0,0 +1,136 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	ingresstemplatev1alpha1 "github.com/takumakume/ingress-template-operator/api/v1alpha1"
+)
+
+// LookupConfig restricts what the lookup template function can read.
+type LookupConfig struct {
+	// Kinds are the kinds of objects templates can look up.
+	Kinds []schema.GroupKind
+
+	// AllNamespaces allows lookups outside the namespace of the IngressTemplate.
+	AllNamespaces bool
+}
+
+func (c LookupConfig) allows(gk schema.GroupKind) bool {
+	for _, kind := range c.Kinds {
+		if kind == gk {
+			return true
+		}
+	}
+	return false
+}
+
+// lookuper implements render.Lookuper for a single render of an IngressTemplate.
+// It records every allowed object it was asked for, including missing ones, so
+// that the IngressTemplate can be rendered again when they change.
+type lookuper struct {
+	ctx       context.Context
+	reader    client.Reader
+	namespace string
+	config    LookupConfig
+
+	refs []ingresstemplatev1alpha1.LookupReference
+}
+
+func (r *IngressTemplateReconciler) newLookuper(ctx context.Context, ingresstemplate *ingresstemplatev1alpha1.IngressTemplate) *lookuper {
+	return &lookuper{
+		ctx:       ctx,
+		reader:    r.Client,
+		namespace: ingresstemplate.Namespace,
+		config:    r.Lookup,
+	}
+}
+
+func (l *lookuper) Lookup(apiVersion, kind, namespace, name string) (map[string]interface{}, error) {
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return nil, err
+	}
+	gvk := gv.WithKind(kind)
+	if !l.config.allows(gvk.GroupKind()) {
+		return nil, fmt.Errorf("lookup of %s is not allowed", gvk.GroupKind())
+	}
+	if namespace == "" {
+		namespace = l.namespace
+	}
+	if namespace != l.namespace && !l.config.AllNamespaces {
+		return nil, fmt.Errorf("lookup in namespace %q is not allowed, only in %q", namespace, l.namespace)
+	}
+
+	l.record(ingresstemplatev1alpha1.LookupReference{
+		APIVersion: apiVersion,
+		Kind:       kind,
+		Namespace:  namespace,
+		Name:       name,
+	})
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	if err := l.reader.Get(l.ctx, client.ObjectKey{Namespace: namespace, Name: name}, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	// Templates can tell whether a Secret exists, but cannot read its values
+	// and expose them in the Ingress.
+	if gvk.GroupKind() == (schema.GroupKind{Kind: "Secret"}) {
+		unstructured.RemoveNestedField(obj.Object, "data")
+		unstructured.RemoveNestedField(obj.Object, "stringData")
+	}
+
+	return obj.Object, nil
+}
+
+func (l *lookuper) record(ref ingresstemplatev1alpha1.LookupReference) {
+	for _, r := range l.refs {
+		if r == ref {
+			return
+		}
+	}
+	l.refs = append(l.refs, ref)
+}
+
+// lookupKey is the value of the lookups index for an object.
+func lookupKey(gk schema.GroupKind, namespace, name string) string {
+	return gk.String() + "/" + namespace + "/" + name
+}
+
+func lookupKeys(refs []ingresstemplatev1alpha1.LookupReference) []string {
+	keys := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		gv, err := schema.ParseGroupVersion(ref.APIVersion)
+		if err != nil {
+			continue
+		}
+		keys = append(keys, lookupKey(gv.WithKind(ref.Kind).GroupKind(), ref.Namespace, ref.Name))
+	}
+	return keys
+}
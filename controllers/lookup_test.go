@@ -0,0 +1,133 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ingresstemplatev1alpha1 "github.com/takumakume/ingress-template-operator/api/v1alpha1"
+)
+
+func Test_lookuper_Lookup(t *testing.T) {
+	reader := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "app"},
+			Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 80}}},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "app"},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "auth"},
+			Data:       map[string][]byte{"token": []byte("s3cr3t")},
+		},
+	).Build()
+
+	type args struct {
+		apiVersion string
+		kind       string
+		namespace  string
+		name       string
+	}
+	tests := []struct {
+		name      string
+		config    LookupConfig
+		args      args
+		wantFound bool
+		wantErr   bool
+		wantRefs  []ingresstemplatev1alpha1.LookupReference
+	}{
+		{
+			name:      "own namespace",
+			config:    LookupConfig{Kinds: []schema.GroupKind{{Kind: "Service"}}},
+			args:      args{"v1", "Service", "", "app"},
+			wantFound: true,
+			wantRefs: []ingresstemplatev1alpha1.LookupReference{
+				{APIVersion: "v1", Kind: "Service", Namespace: "ns", Name: "app"},
+			},
+		},
+		{
+			name:   "not found is recorded",
+			config: LookupConfig{Kinds: []schema.GroupKind{{Kind: "Service"}}},
+			args:   args{"v1", "Service", "ns", "missing"},
+			wantRefs: []ingresstemplatev1alpha1.LookupReference{
+				{APIVersion: "v1", Kind: "Service", Namespace: "ns", Name: "missing"},
+			},
+		},
+		{
+			name:    "kind not allowed",
+			config:  LookupConfig{Kinds: []schema.GroupKind{{Kind: "Service"}}},
+			args:    args{"v1", "Secret", "", "auth"},
+			wantErr: true,
+		},
+		{
+			name:    "other namespace not allowed",
+			config:  LookupConfig{Kinds: []schema.GroupKind{{Kind: "Service"}}},
+			args:    args{"v1", "Service", "other", "app"},
+			wantErr: true,
+		},
+		{
+			name:      "other namespace allowed",
+			config:    LookupConfig{Kinds: []schema.GroupKind{{Kind: "Service"}}, AllNamespaces: true},
+			args:      args{"v1", "Service", "other", "app"},
+			wantFound: true,
+			wantRefs: []ingresstemplatev1alpha1.LookupReference{
+				{APIVersion: "v1", Kind: "Service", Namespace: "other", Name: "app"},
+			},
+		},
+		{
+			name:      "secret values are left out",
+			config:    LookupConfig{Kinds: []schema.GroupKind{{Kind: "Secret"}}},
+			args:      args{"v1", "Secret", "", "auth"},
+			wantFound: true,
+			wantRefs: []ingresstemplatev1alpha1.LookupReference{
+				{APIVersion: "v1", Kind: "Secret", Namespace: "ns", Name: "auth"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &lookuper{
+				ctx:       context.Background(),
+				reader:    reader,
+				namespace: "ns",
+				config:    tt.config,
+			}
+			got, err := l.Lookup(tt.args.apiVersion, tt.args.kind, tt.args.namespace, tt.args.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("lookuper.Lookup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (got != nil) != tt.wantFound {
+				t.Errorf("lookuper.Lookup() = %v, wantFound %v", got, tt.wantFound)
+			}
+			if !reflect.DeepEqual(l.refs, tt.wantRefs) {
+				t.Errorf("lookuper.refs = %v, want %v", l.refs, tt.wantRefs)
+			}
+			if _, ok := got["data"]; ok && tt.args.kind == "Secret" {
+				t.Errorf("lookuper.Lookup() = %v, want the data of the Secret left out", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/structured-merge-diff/v4/fieldpath"
+)
+
+// legacyFieldManager is the field manager of the Ingresses that versions of the
+// operator before server-side apply created and updated. The API server names
+// it after the operator binary.
+const legacyFieldManager = "manager"
+
+// upgradeManagedFields hands the fields of current that the legacy field
+// manager owns over to the server-side apply of the operator, so that the next
+// apply removes those it no longer renders. The managed fields are replaced only
+// when current was not changed in the meantime.
+func (r *IngressTemplateReconciler) upgradeManagedFields(ctx context.Context, current *networkingv1.Ingress) error {
+	entries, upgraded, err := upgradeManagedFields(current.ManagedFields)
+	if err != nil || !upgraded {
+		return err
+	}
+	patch, err := json.Marshal([]map[string]interface{}{
+		{"op": "test", "path": "/metadata/resourceVersion", "value": current.ResourceVersion},
+		{"op": "replace", "path": "/metadata/managedFields", "value": entries},
+	})
+	if err != nil {
+		return err
+	}
+	log.FromContext(ctx).Info("move the fields of the legacy field manager to server-side apply")
+	return r.Patch(ctx, current, client.RawPatch(types.JSONPatchType, patch))
+}
+
+// upgradeManagedFields returns entries with the fields the legacy field manager
+// updated merged into the apply entry of fieldManager, and whether there were
+// any.
+func upgradeManagedFields(entries []metav1.ManagedFieldsEntry) ([]metav1.ManagedFieldsEntry, bool, error) {
+	fields := &fieldpath.Set{}
+	upgraded := make([]metav1.ManagedFieldsEntry, 0, len(entries)+1)
+	applied := -1
+	for _, entry := range entries {
+		if entry.Subresource == "" && entry.Manager == legacyFieldManager && entry.Operation == metav1.ManagedFieldsOperationUpdate {
+			set, err := fieldSet(entry.FieldsV1)
+			if err != nil {
+				return nil, false, err
+			}
+			fields = fields.Union(set)
+			continue
+		}
+		if entry.Subresource == "" && entry.Manager == fieldManager && entry.Operation == metav1.ManagedFieldsOperationApply {
+			applied = len(upgraded)
+		}
+		upgraded = append(upgraded, entry)
+	}
+	if len(upgraded) == len(entries) {
+		return entries, false, nil
+	}
+
+	if applied < 0 {
+		now := metav1.Now()
+		upgraded = append(upgraded, metav1.ManagedFieldsEntry{
+			Manager:    fieldManager,
+			Operation:  metav1.ManagedFieldsOperationApply,
+			APIVersion: networkingv1.SchemeGroupVersion.String(),
+			Time:       &now,
+			FieldsType: "FieldsV1",
+		})
+		applied = len(upgraded) - 1
+	} else {
+		set, err := fieldSet(upgraded[applied].FieldsV1)
+		if err != nil {
+			return nil, false, err
+		}
+		fields = fields.Union(set)
+	}
+	raw, err := fields.ToJSON()
+	if err != nil {
+		return nil, false, err
+	}
+	upgraded[applied].FieldsV1 = &metav1.FieldsV1{Raw: raw}
+	return upgraded, true, nil
+}
+
+func fieldSet(fields *metav1.FieldsV1) (*fieldpath.Set, error) {
+	set := &fieldpath.Set{}
+	if fields == nil {
+		return set, nil
+	}
+	if err := set.FromJSON(bytes.NewReader(fields.Raw)); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
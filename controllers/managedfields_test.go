@@ -0,0 +1,157 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func fieldsV1(t *testing.T, fields map[string]interface{}) *metav1.FieldsV1 {
+	t.Helper()
+	raw, err := json.Marshal(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &metav1.FieldsV1{Raw: raw}
+}
+
+func Test_upgradeManagedFields(t *testing.T) {
+	legacy := metav1.ManagedFieldsEntry{
+		Manager:    legacyFieldManager,
+		Operation:  metav1.ManagedFieldsOperationUpdate,
+		APIVersion: "networking.k8s.io/v1",
+		FieldsType: "FieldsV1",
+		FieldsV1:   fieldsV1(t, map[string]interface{}{"f:metadata": map[string]interface{}{"f:annotations": map[string]interface{}{"f:legacy": map[string]interface{}{}}}}),
+	}
+	other := metav1.ManagedFieldsEntry{
+		Manager:    "cert-manager",
+		Operation:  metav1.ManagedFieldsOperationUpdate,
+		APIVersion: "networking.k8s.io/v1",
+		FieldsType: "FieldsV1",
+		FieldsV1:   fieldsV1(t, map[string]interface{}{"f:metadata": map[string]interface{}{"f:annotations": map[string]interface{}{"f:cert-manager.io/issuer": map[string]interface{}{}}}}),
+	}
+	applied := metav1.ManagedFieldsEntry{
+		Manager:    fieldManager,
+		Operation:  metav1.ManagedFieldsOperationApply,
+		APIVersion: "networking.k8s.io/v1",
+		FieldsType: "FieldsV1",
+		FieldsV1:   fieldsV1(t, map[string]interface{}{"f:spec": map[string]interface{}{"f:ingressClassName": map[string]interface{}{}}}),
+	}
+
+	tests := []struct {
+		name         string
+		entries      []metav1.ManagedFieldsEntry
+		wantUpgraded bool
+		want         []string
+		wantFields   map[string]interface{}
+	}{
+		{
+			name:    "no legacy fields",
+			entries: []metav1.ManagedFieldsEntry{other, applied},
+			want:    []string{"cert-manager", fieldManager},
+		},
+		{
+			name:         "new apply entry",
+			entries:      []metav1.ManagedFieldsEntry{legacy, other},
+			wantUpgraded: true,
+			want:         []string{"cert-manager", fieldManager},
+			wantFields:   map[string]interface{}{"f:metadata": map[string]interface{}{"f:annotations": map[string]interface{}{"f:legacy": map[string]interface{}{}}}},
+		},
+		{
+			name:         "merged into the apply entry",
+			entries:      []metav1.ManagedFieldsEntry{applied, legacy, other},
+			wantUpgraded: true,
+			want:         []string{fieldManager, "cert-manager"},
+			wantFields: map[string]interface{}{
+				"f:metadata": map[string]interface{}{"f:annotations": map[string]interface{}{"f:legacy": map[string]interface{}{}}},
+				"f:spec":     map[string]interface{}{"f:ingressClassName": map[string]interface{}{}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, upgraded, err := upgradeManagedFields(tt.entries)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if upgraded != tt.wantUpgraded {
+				t.Errorf("upgradeManagedFields() upgraded = %v, want %v", upgraded, tt.wantUpgraded)
+			}
+			var managers []string
+			for _, entry := range got {
+				managers = append(managers, entry.Manager)
+				if entry.Manager != fieldManager || tt.wantFields == nil {
+					continue
+				}
+				if entry.Operation != metav1.ManagedFieldsOperationApply {
+					t.Errorf("operation of %s = %s, want Apply", fieldManager, entry.Operation)
+				}
+				var fields map[string]interface{}
+				if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(fields, tt.wantFields) {
+					t.Errorf("fields of %s = %v, want %v", fieldManager, fields, tt.wantFields)
+				}
+			}
+			if !reflect.DeepEqual(managers, tt.want) {
+				t.Errorf("managers = %v, want %v", managers, tt.want)
+			}
+		})
+	}
+}
+
+func TestIngressTemplateReconciler_upgradeManagedFields(t *testing.T) {
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "upgrade",
+			Name:        "app",
+			Annotations: map[string]string{"legacy": "true"},
+			ManagedFields: []metav1.ManagedFieldsEntry{{
+				Manager:    legacyFieldManager,
+				Operation:  metav1.ManagedFieldsOperationUpdate,
+				APIVersion: "networking.k8s.io/v1",
+				FieldsType: "FieldsV1",
+				FieldsV1:   fieldsV1(t, map[string]interface{}{"f:metadata": map[string]interface{}{"f:annotations": map[string]interface{}{"f:legacy": map[string]interface{}{}}}}),
+			}},
+		},
+	}
+	r, c, _ := newFakeReconciler(t, ingress)
+
+	current := &networkingv1.Ingress{}
+	if err := c.Get(context.Background(), client.ObjectKeyFromObject(ingress), current); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.upgradeManagedFields(context.Background(), current); err != nil {
+		t.Fatal(err)
+	}
+
+	got := &networkingv1.Ingress{}
+	if err := c.Get(context.Background(), client.ObjectKeyFromObject(ingress), got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.ManagedFields) != 1 || got.ManagedFields[0].Manager != fieldManager || got.ManagedFields[0].Operation != metav1.ManagedFieldsOperationApply {
+		t.Errorf("managed fields = %+v, want the fields applied by %s", got.ManagedFields, fieldManager)
+	}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"sigs.k8s.io/yaml"
+
+	ingresstemplatev1alpha1 "github.com/takumakume/ingress-template-operator/api/v1alpha1"
+	"github.com/takumakume/ingress-template-operator/pkg/render"
+)
+
+// PatchError reports a patch of spec.patches, at Index, that could not be
+// rendered or applied, or that produced an invalid Ingress.
+type PatchError struct {
+	Index int
+	Err   error
+}
+
+func (e *PatchError) Error() string {
+	return fmt.Sprintf("patch %d: %s", e.Index, e.Err)
+}
+
+func (e *PatchError) Unwrap() error {
+	return e.Err
+}
+
+// applyPatches renders the patches and applies them in order to ing.
+func applyPatches(ctx context.Context, ing *networkingv1.Ingress, patches []ingresstemplatev1alpha1.Patch, opt render.Options) (*networkingv1.Ingress, error) {
+	for i, p := range patches {
+		patched, err := applyPatch(ctx, ing, fmt.Sprintf("spec.patches[%d]", i), p, opt)
+		if err != nil {
+			return nil, &PatchError{Index: i, Err: err}
+		}
+		ing = patched
+	}
+	return ing, nil
+}
+
+func applyPatch(ctx context.Context, ing *networkingv1.Ingress, path string, p ingresstemplatev1alpha1.Patch, opt render.Options) (*networkingv1.Ingress, error) {
+	rendered, err := render.RenderString(ctx, path+".patch", p.Patch, opt)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(rendered) == "" {
+		return ing, nil
+	}
+	patch, err := yaml.YAMLToJSON([]byte(rendered))
+	if err != nil {
+		return nil, &render.FieldError{Path: path + ".patch", Err: err}
+	}
+
+	current, err := json.Marshal(ing)
+	if err != nil {
+		return nil, err
+	}
+	var out []byte
+	switch p.Type {
+	case ingresstemplatev1alpha1.PatchTypeJSONPatch:
+		var jp jsonpatch.Patch
+		if jp, err = jsonpatch.DecodePatch(patch); err == nil {
+			out, err = jp.Apply(current)
+		}
+	default:
+		out, err = strategicpatch.StrategicMergePatch(current, patch, networkingv1.Ingress{})
+	}
+	if err != nil {
+		return nil, &render.FieldError{Path: path + ".patch", Err: err}
+	}
+
+	patched := &networkingv1.Ingress{}
+	dec := json.NewDecoder(bytes.NewReader(out))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(patched); err != nil {
+		return nil, &render.FieldError{Path: path + ".patch", Err: fmt.Errorf("patched Ingress: %w", err)}
+	}
+	if patched.Name != ing.Name || patched.Namespace != ing.Namespace {
+		return nil, &render.FieldError{Path: path + ".patch", Err: fmt.Errorf("a patch can not change the name or namespace of the Ingress")}
+	}
+	if err := render.Validate(patched); err != nil {
+		return nil, &render.FieldError{Path: path + ".patch", Err: fmt.Errorf("patched Ingress is invalid: %w", err)}
+	}
+	return patched, nil
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ingresstemplatev1alpha1 "github.com/takumakume/ingress-template-operator/api/v1alpha1"
+	"github.com/takumakume/ingress-template-operator/pkg/render"
+)
+
+func Test_applyPatches(t *testing.T) {
+	ingress := func() *networkingv1.Ingress {
+		return &networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "app",
+				Namespace:   "team-a",
+				Annotations: map[string]string{"example.com/owner": "platform"},
+			},
+			Spec: networkingv1.IngressSpec{
+				Rules: []networkingv1.IngressRule{{Host: "app.example.com"}, {Host: "www.example.com"}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		patches   []ingresstemplatev1alpha1.Patch
+		want      *networkingv1.Ingress
+		wantIndex int
+		wantPath  string
+	}{
+		{
+			name: "strategic merge and JSON patch",
+			patches: []ingresstemplatev1alpha1.Patch{
+				{Patch: "metadata:\n  annotations:\n    example.com/namespace: '{{ .Metadata.Namespace }}'"},
+				{Patch: `{{ if eq .Metadata.Namespace "production" }}metadata: {labels: {tier: prod}}{{ end }}`},
+				{Type: ingresstemplatev1alpha1.PatchTypeJSONPatch, Patch: `[{"op": "remove", "path": "/spec/rules/1"}]`},
+			},
+			want: &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "app",
+					Namespace: "team-a",
+					Annotations: map[string]string{
+						"example.com/owner":     "platform",
+						"example.com/namespace": "team-a",
+					},
+				},
+				Spec: networkingv1.IngressSpec{
+					Rules: []networkingv1.IngressRule{{Host: "app.example.com"}},
+				},
+			},
+		},
+		{
+			name: "render error",
+			patches: []ingresstemplatev1alpha1.Patch{
+				{Patch: "metadata: {}"},
+				{Patch: "{{ .Values.missing.key }}"},
+			},
+			wantIndex: 1,
+			wantPath:  "spec.patches[1].patch",
+		},
+		{
+			name: "failing JSON patch",
+			patches: []ingresstemplatev1alpha1.Patch{
+				{Type: ingresstemplatev1alpha1.PatchTypeJSONPatch, Patch: `[{"op": "remove", "path": "/spec/tls"}]`},
+			},
+			wantPath: "spec.patches[0].patch",
+		},
+		{
+			name: "unknown field",
+			patches: []ingresstemplatev1alpha1.Patch{
+				{Patch: "spec: {rulez: []}"},
+			},
+			wantPath: "spec.patches[0].patch",
+		},
+		{
+			name: "invalid Ingress",
+			patches: []ingresstemplatev1alpha1.Patch{
+				{Type: ingresstemplatev1alpha1.PatchTypeJSONPatch, Patch: `[{"op": "replace", "path": "/spec/rules/0/host", "value": "app_1.example.com"}]`},
+			},
+			wantPath: "spec.patches[0].patch",
+		},
+		{
+			name: "renamed Ingress",
+			patches: []ingresstemplatev1alpha1.Patch{
+				{Patch: "metadata: {name: other}"},
+			},
+			wantPath: "spec.patches[0].patch",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opt := render.Options{Metadata: metav1.ObjectMeta{Name: "app", Namespace: "team-a"}}
+			got, err := applyPatches(context.Background(), ingress(), tt.patches, opt)
+			if tt.wantPath != "" {
+				var patchErr *PatchError
+				if !errors.As(err, &patchErr) || patchErr.Index != tt.wantIndex {
+					t.Fatalf("applyPatches() error = %v, want an error of patch %d", err, tt.wantIndex)
+				}
+				var fieldErr *render.FieldError
+				if !errors.As(err, &fieldErr) || fieldErr.Path != tt.wantPath {
+					t.Errorf("applyPatches() error = %v, want an error for %s", err, tt.wantPath)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyPatches() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("applyPatches() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	ingresstemplatev1alpha1 "github.com/takumakume/ingress-template-operator/api/v1alpha1"
+)
+
+// previewKey is the key of the preview ConfigMap holding the rendered Ingress.
+const previewKey = "ingress.yaml"
+
+// previewConfigMapKey returns the name of the ConfigMap the Ingress rendered
+// from ingresstemplate in dry-run mode is published in.
+func previewConfigMapKey(ingresstemplate *ingresstemplatev1alpha1.IngressTemplate) types.NamespacedName {
+	return types.NamespacedName{Namespace: ingresstemplate.Namespace, Name: ingresstemplate.Name + "-preview"}
+}
+
+// publishPreview writes the Ingress that result would apply to the preview
+// ConfigMap of ingresstemplate, with Secret values hidden by redact. A ConfigMap
+// of the same name that ingresstemplate does not control is left alone.
+func (r *IngressTemplateReconciler) publishPreview(ctx context.Context, ingresstemplate *ingresstemplatev1alpha1.IngressTemplate, result applyResult, redact *redactor) (*ingresstemplatev1alpha1.Preview, error) {
+	key := previewConfigMapKey(ingresstemplate)
+	existing := &corev1.ConfigMap{}
+	if err := r.Get(ctx, key, existing); client.IgnoreNotFound(err) != nil {
+		return nil, err
+	} else if err == nil && !metav1.IsControlledBy(existing, ingresstemplate) {
+		return nil, fmt.Errorf("ConfigMap %s already exists and is not the preview of this IngressTemplate", key.Name)
+	}
+
+	data, err := yaml.Marshal(result.obj.Object)
+	if err != nil {
+		return nil, err
+	}
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("ConfigMap")
+	obj.SetNamespace(key.Namespace)
+	obj.SetName(key.Name)
+	obj.SetOwnerReferences([]metav1.OwnerReference{
+		*metav1.NewControllerRef(ingresstemplate, ingresstemplatev1alpha1.GroupVersion.WithKind("IngressTemplate")),
+	})
+	if err := unstructured.SetNestedStringMap(obj.Object, map[string]string{previewKey: redact.String(string(data))}, "data"); err != nil {
+		return nil, err
+	}
+	if err := r.Patch(ctx, obj, client.Apply, client.FieldOwner(fieldManager), client.ForceOwnership); err != nil {
+		return nil, err
+	}
+	return &ingresstemplatev1alpha1.Preview{Hash: result.hash, ConfigMapName: key.Name}, nil
+}
+
+// deletePreview deletes the preview ConfigMap of ingresstemplate, if there is one.
+func (r *IngressTemplateReconciler) deletePreview(ctx context.Context, ingresstemplate *ingresstemplatev1alpha1.IngressTemplate) error {
+	cm := &corev1.ConfigMap{}
+	if err := r.Get(ctx, previewConfigMapKey(ingresstemplate), cm); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if !metav1.IsControlledBy(cm, ingresstemplate) {
+		return nil
+	}
+	return client.IgnoreNotFound(r.Delete(ctx, cm))
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	ingresstemplatev1alpha1 "github.com/takumakume/ingress-template-operator/api/v1alpha1"
+)
+
+func TestIngressTemplateReconciler_Reconcile_dryRun(t *testing.T) {
+	ingresstemplate := &ingresstemplatev1alpha1.IngressTemplate{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "preview", Name: "app", UID: "uid"},
+		Spec: ingresstemplatev1alpha1.IngressTemplateSpec{
+			IngressSpecTemplate: ingresstemplatev1alpha1.IngressSpecTemplate{
+				Rules: []ingresstemplatev1alpha1.IngressRuleTemplate{{Host: "{{ .Metadata.Name }}.example.com"}},
+			},
+			DryRun: true,
+		},
+	}
+	r, c, _ := newFakeReconciler(t,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "preview"}},
+		ingresstemplate,
+	)
+	key := client.ObjectKeyFromObject(ingresstemplate)
+	previewKey := previewConfigMapKey(ingresstemplate)
+
+	reconcile := func() *ingresstemplatev1alpha1.IngressTemplate {
+		t.Helper()
+		if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key}); err != nil {
+			t.Fatal(err)
+		}
+		got := &ingresstemplatev1alpha1.IngressTemplate{}
+		if err := c.Get(context.Background(), key, got); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	got := reconcile()
+	if err := c.Get(context.Background(), key, &networkingv1.Ingress{}); !apierrors.IsNotFound(err) {
+		t.Errorf("get Ingress in dry-run mode: %v, want it not created", err)
+	}
+	if got.Status.Preview == nil || got.Status.Preview.Hash == "" || got.Status.Preview.ConfigMapName != previewKey.Name {
+		t.Fatalf("status.preview = %+v, want the hash and ConfigMap %s", got.Status.Preview, previewKey.Name)
+	}
+	cm := &corev1.ConfigMap{}
+	if err := c.Get(context.Background(), previewKey, cm); err != nil {
+		t.Fatalf("preview ConfigMap was not created: %v", err)
+	}
+	if !strings.Contains(cm.Data["ingress.yaml"], "host: app.example.com") {
+		t.Errorf("preview = %s, want the rendered host", cm.Data["ingress.yaml"])
+	}
+	if !metav1.IsControlledBy(cm, got) {
+		t.Errorf("preview ConfigMap owners = %+v, want the IngressTemplate", cm.OwnerReferences)
+	}
+	applied := meta.FindStatusCondition(got.Status.Conditions, ingresstemplatev1alpha1.ConditionTypeApplied)
+	if applied == nil || applied.Reason != ingresstemplatev1alpha1.ReasonDryRun {
+		t.Errorf("Applied condition = %+v, want reason DryRun", applied)
+	}
+	hash := got.Status.Preview.Hash
+
+	got.Spec.DryRun = false
+	if err := c.Update(context.Background(), got); err != nil {
+		t.Fatal(err)
+	}
+	got = reconcile()
+	ingress := &networkingv1.Ingress{}
+	if err := c.Get(context.Background(), key, ingress); err != nil {
+		t.Fatalf("Ingress was not created after dry-run was turned off: %v", err)
+	}
+	if ingress.Annotations[renderedHashAnnotation] != hash {
+		t.Errorf("Ingress rendered hash = %s, want the previewed %s", ingress.Annotations[renderedHashAnnotation], hash)
+	}
+	if got.Status.Preview != nil {
+		t.Errorf("status.preview = %+v, want it unset", got.Status.Preview)
+	}
+	if err := c.Get(context.Background(), previewKey, &corev1.ConfigMap{}); !apierrors.IsNotFound(err) {
+		t.Errorf("get preview ConfigMap: %v, want it deleted", err)
+	}
+}
+
+func TestIngressTemplateReconciler_Reconcile_dryRunSecret(t *testing.T) {
+	ingresstemplate := &ingresstemplatev1alpha1.IngressTemplate{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "preview-secret", Name: "app", UID: "uid"},
+		Spec: ingresstemplatev1alpha1.IngressTemplateSpec{
+			IngressAnnotations: map[string]string{"auth": "{{ .Secrets.auth.token }}"},
+			SecretRefs:         []corev1.LocalObjectReference{{Name: "auth"}},
+			IngressSpecTemplate: ingresstemplatev1alpha1.IngressSpecTemplate{
+				Rules: []ingresstemplatev1alpha1.IngressRuleTemplate{{Host: "{{ .Metadata.Name }}.example.com"}},
+			},
+			DryRun: true,
+		},
+	}
+	r, c, _ := newFakeReconciler(t,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "preview-secret"}},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "preview-secret", Name: "auth"},
+			Data:       map[string][]byte{"token": []byte("s3cr3t-t0ken")},
+		},
+		ingresstemplate,
+	)
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ingresstemplate)}); err != nil {
+		t.Fatal(err)
+	}
+
+	cm := &corev1.ConfigMap{}
+	if err := c.Get(context.Background(), previewConfigMapKey(ingresstemplate), cm); err != nil {
+		t.Fatalf("preview ConfigMap was not created: %v", err)
+	}
+	if preview := cm.Data[previewKey]; strings.Contains(preview, "s3cr3t-t0ken") || !strings.Contains(preview, redacted) {
+		t.Errorf("preview = %s, want the Secret value redacted", preview)
+	}
+}
+
+func TestIngressTemplateReconciler_publishPreview_foreignConfigMap(t *testing.T) {
+	ingresstemplate := &ingresstemplatev1alpha1.IngressTemplate{ObjectMeta: metav1.ObjectMeta{Namespace: "preview", Name: "app", UID: "uid"}}
+	foreign := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "preview", Name: "app-preview"}}
+	r, c, _ := newFakeReconciler(t, foreign)
+
+	if _, err := r.publishPreview(context.Background(), ingresstemplate, applyResult{}, newRedactor()); err == nil {
+		t.Errorf("publishPreview() error = nil, want an error for a ConfigMap the IngressTemplate does not control")
+	}
+	if err := r.deletePreview(context.Background(), ingresstemplate); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Get(context.Background(), client.ObjectKeyFromObject(foreign), &corev1.ConfigMap{}); err != nil {
+		t.Errorf("deletePreview() deleted a ConfigMap the IngressTemplate does not control: %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"sort"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// minSubstringLength is the length a Secret value needs to be hidden wherever
+// it appears. Shorter values, such as "1" or "true", are only hidden where they
+// stand alone, as hiding them inside other words would garble unrelated parts
+// of messages.
+const minSubstringLength = 6
+
+// redactor hides Secret values in messages written to logs, events and the status.
+type redactor struct {
+	values []string
+}
+
+func newRedactor(secrets ...map[string]map[string]string) *redactor {
+	r := &redactor{}
+	for _, s := range secrets {
+		for _, data := range s {
+			for _, v := range data {
+				if v != "" {
+					r.values = append(r.values, v)
+				}
+			}
+		}
+	}
+	// replace longer values first so that a value containing another one is hidden as a whole
+	sort.Slice(r.values, func(i, j int) bool {
+		return len(r.values[i]) > len(r.values[j])
+	})
+	return r
+}
+
+func (r *redactor) String(s string) string {
+	for _, v := range r.values {
+		if len(v) >= minSubstringLength {
+			s = strings.ReplaceAll(s, v, redacted)
+		} else {
+			s = replaceWord(s, v)
+		}
+	}
+	return s
+}
+
+// replaceWord replaces the occurrences of v in s that are not part of a longer
+// word with redacted.
+func replaceWord(s, v string) string {
+	var b strings.Builder
+	last := 0
+	for i := 0; i <= len(s)-len(v); {
+		j := strings.Index(s[i:], v)
+		if j < 0 {
+			break
+		}
+		start, end := i+j, i+j+len(v)
+		if (start == 0 || !isWordByte(s[start-1])) && (end == len(s) || !isWordByte(s[end])) {
+			b.WriteString(s[last:start])
+			b.WriteString(redacted)
+			last, i = end, end
+		} else {
+			i = start + 1
+		}
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// isWordByte reports whether c belongs to a word. Bytes of multi-byte
+// characters do, so that they are never split.
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// Error returns err with Secret values hidden from its message. The original
+// error is still available through errors.As and errors.Is.
+func (r *redactor) Error(err error) error {
+	if err == nil || len(r.values) == 0 {
+		return err
+	}
+	return &redactedError{msg: r.String(err.Error()), err: err}
+}
+
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
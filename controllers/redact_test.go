@@ -0,0 +1,95 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func Test_redactor(t *testing.T) {
+	secrets := map[string]map[string]string{
+		"secret1": {
+			"password": "p@ssw0",
+			"token":    "p@ssw0rd",
+			"empty":    "",
+			"short":    "1",
+			"flag":     "true",
+		},
+	}
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{
+			name: "no secret",
+			s:    "hoge",
+			want: "hoge",
+		},
+		{
+			name: "secret",
+			s:    "host p@ssw0.example.com",
+			want: "host [REDACTED].example.com",
+		},
+		{
+			name: "longer secret first",
+			s:    "p@ssw0rd and p@ssw0",
+			want: "[REDACTED] and [REDACTED]",
+		},
+		{
+			name: "short values",
+			s:    "line 1, column 12: true",
+			want: "line [REDACTED], column 12: [REDACTED]",
+		},
+		{
+			name: "short values inside words are kept",
+			s:    "truest 21",
+			want: "truest 21",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRedactor(secrets)
+			if got := r.String(tt.s); got != tt.want {
+				t.Errorf("redactor.String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_redactor_Error(t *testing.T) {
+	base := errors.New("base")
+	err := fmt.Errorf("invalid host %q: %w", "p@ssw0.example.com", base)
+
+	r := newRedactor(map[string]map[string]string{"secret1": {"password": "p@ssw0"}})
+	got := r.Error(err)
+	if got.Error() != `invalid host "[REDACTED].example.com": base` {
+		t.Errorf("redactor.Error() = %v", got)
+	}
+	if !errors.Is(got, base) {
+		t.Errorf("redactor.Error() does not wrap the original error")
+	}
+
+	if r.Error(nil) != nil {
+		t.Errorf("redactor.Error(nil) is not nil")
+	}
+	if got := newRedactor(nil).Error(err); got != err {
+		t.Errorf("redactor.Error() without secrets = %v, want %v", got, err)
+	}
+}
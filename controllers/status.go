@@ -0,0 +1,268 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ingresstemplatev1alpha1 "github.com/takumakume/ingress-template-operator/api/v1alpha1"
+	"github.com/takumakume/ingress-template-operator/pkg/parameters"
+	"github.com/takumakume/ingress-template-operator/pkg/render"
+)
+
+// observation is what a reconcile found out about an IngressTemplate.
+type observation struct {
+	// rendered and applied are the Rendered and Applied conditions.
+	rendered metav1.Condition
+	applied  metav1.Condition
+
+	// renderErr locates the error of a failed render.
+	renderErr *ingresstemplatev1alpha1.RenderError
+
+	// lookups are the objects read by the render.
+	lookups []ingresstemplatev1alpha1.LookupReference
+
+	// ingress is the Ingress in the cluster, or nil when there is none.
+	ingress *networkingv1.Ingress
+
+	// drift is how ingress differs from the rendered one in observe-only mode.
+	drift *ingresstemplatev1alpha1.Drift
+
+	// preview is the Ingress rendered in dry-run mode.
+	preview *ingresstemplatev1alpha1.Preview
+}
+
+// setStatus records what the reconcile observed on the status of
+// ingresstemplate, together with the Ready and Degraded conditions derived from
+// it. The status is only written when it actually changed.
+func (r *IngressTemplateReconciler) setStatus(ctx context.Context, ingresstemplate *ingresstemplatev1alpha1.IngressTemplate, o observation) error {
+	status := ingresstemplate.Status.DeepCopy()
+	generation := ingresstemplate.Generation
+
+	ready := readyCondition(o.rendered, o.applied)
+	for _, cond := range []metav1.Condition{o.rendered, o.applied, ready, degradedCondition(ready, o.ingress != nil)} {
+		cond.ObservedGeneration = generation
+		meta.SetStatusCondition(&status.Conditions, cond)
+	}
+	status.Ready = corev1.ConditionStatus(ready.Status)
+	status.ObservedGeneration = generation
+	status.RenderError = o.renderErr
+	status.Lookups = o.lookups
+	status.Drift = o.drift
+	status.Preview = o.preview
+	status.IngressRef = nil
+	if o.ingress != nil {
+		status.IngressRef = &ingresstemplatev1alpha1.IngressReference{Name: o.ingress.Name, UID: o.ingress.UID}
+	}
+
+	if equality.Semantic.DeepEqual(&ingresstemplate.Status, status) {
+		return nil
+	}
+	ingresstemplate.Status = *status
+	return r.Status().Update(ctx, ingresstemplate)
+}
+
+// readyCondition is True when the Ingress was rendered and applied, and
+// otherwise False with the reason of the first of them that failed.
+func readyCondition(rendered, applied metav1.Condition) metav1.Condition {
+	for _, cond := range []metav1.Condition{rendered, applied} {
+		if cond.Status != metav1.ConditionTrue {
+			return metav1.Condition{
+				Type:    ingresstemplatev1alpha1.ConditionTypeReady,
+				Status:  metav1.ConditionFalse,
+				Reason:  cond.Reason,
+				Message: cond.Message,
+			}
+		}
+	}
+	return metav1.Condition{
+		Type:   ingresstemplatev1alpha1.ConditionTypeReady,
+		Status: metav1.ConditionTrue,
+		Reason: ingresstemplatev1alpha1.ReasonReconciled,
+	}
+}
+
+// degradedCondition is True when the IngressTemplate is not ready but an
+// Ingress generated by an earlier reconcile is still in place.
+func degradedCondition(ready metav1.Condition, hasIngress bool) metav1.Condition {
+	cond := metav1.Condition{
+		Type:   ingresstemplatev1alpha1.ConditionTypeDegraded,
+		Status: metav1.ConditionFalse,
+		Reason: ingresstemplatev1alpha1.ReasonReconciled,
+	}
+	switch {
+	case ready.Status == metav1.ConditionTrue:
+	case ready.Reason == ingresstemplatev1alpha1.ReasonObserveOnly, ready.Reason == ingresstemplatev1alpha1.ReasonDryRun:
+		cond.Reason = ready.Reason
+		cond.Message = ready.Message
+	case hasIngress:
+		cond.Status = metav1.ConditionTrue
+		cond.Reason = ready.Reason
+		cond.Message = "the Ingress of an earlier reconcile is still in place: " + ready.Message
+	default:
+		cond.Reason = ingresstemplatev1alpha1.ReasonIngressNotCreated
+		cond.Message = "no Ingress has been created yet"
+	}
+	return cond
+}
+
+// appliedCondition returns the Applied condition for the result of writing the
+// rendered Ingress to the cluster. Fields of the Ingress owned by another field
+// manager are reported as an ApplyConflict.
+func appliedCondition(err error) metav1.Condition {
+	if err != nil {
+		reason := ingresstemplatev1alpha1.ReasonApplyFailed
+		if apierrors.IsConflict(err) {
+			reason = ingresstemplatev1alpha1.ReasonApplyConflict
+		}
+		return metav1.Condition{
+			Type:    ingresstemplatev1alpha1.ConditionTypeApplied,
+			Status:  metav1.ConditionFalse,
+			Reason:  reason,
+			Message: err.Error(),
+		}
+	}
+	return metav1.Condition{
+		Type:   ingresstemplatev1alpha1.ConditionTypeApplied,
+		Status: metav1.ConditionTrue,
+		Reason: ingresstemplatev1alpha1.ReasonApplySucceeded,
+	}
+}
+
+// observedCondition is the Applied condition in observe-only mode. It is True
+// when the Ingress in the cluster already is the rendered one.
+func observedCondition(drift *ingresstemplatev1alpha1.Drift) metav1.Condition {
+	if drift.Count > 0 {
+		return metav1.Condition{
+			Type:    ingresstemplatev1alpha1.ConditionTypeApplied,
+			Status:  metav1.ConditionFalse,
+			Reason:  ingresstemplatev1alpha1.ReasonObserveOnly,
+			Message: fmt.Sprintf("%d fields of the Ingress differ from the rendered ones and are not written in observe-only mode", drift.Count),
+		}
+	}
+	return metav1.Condition{
+		Type:    ingresstemplatev1alpha1.ConditionTypeApplied,
+		Status:  metav1.ConditionTrue,
+		Reason:  ingresstemplatev1alpha1.ReasonObserveOnly,
+		Message: "the Ingress in the cluster is the rendered one",
+	}
+}
+
+// dryRunCondition is the Applied condition in dry-run mode, for the result of
+// publishing the rendered Ingress.
+func dryRunCondition(preview *ingresstemplatev1alpha1.Preview, err error) metav1.Condition {
+	if err != nil {
+		return appliedCondition(err)
+	}
+	return metav1.Condition{
+		Type:    ingresstemplatev1alpha1.ConditionTypeApplied,
+		Status:  metav1.ConditionFalse,
+		Reason:  ingresstemplatev1alpha1.ReasonDryRun,
+		Message: fmt.Sprintf("the Ingress is not written in dry-run mode; the rendered Ingress is in ConfigMap %s", preview.ConfigMapName),
+	}
+}
+
+// notAppliedCondition is the Applied condition of a reconcile whose render failed.
+func notAppliedCondition() metav1.Condition {
+	return metav1.Condition{
+		Type:    ingresstemplatev1alpha1.ConditionTypeApplied,
+		Status:  metav1.ConditionFalse,
+		Reason:  ingresstemplatev1alpha1.ReasonNotRendered,
+		Message: "the Ingress is not updated until the templates render",
+	}
+}
+
+func renderedCondition(err error) metav1.Condition {
+	if err != nil {
+		reason := ingresstemplatev1alpha1.ReasonRenderFailed
+		var (
+			paramsErr  *parameters.Error
+			timeoutErr *render.TimeoutError
+			sizeErr    *render.OutputSizeError
+			depthErr   *render.DepthError
+			seqErr     *render.SequenceLengthError
+			patchErr   *PatchError
+		)
+		switch {
+		case errors.As(err, &paramsErr):
+			reason = ingresstemplatev1alpha1.ReasonInvalidParameters
+		case errors.As(err, &patchErr):
+			reason = ingresstemplatev1alpha1.ReasonPatchFailed
+		case errors.As(err, &timeoutErr), errors.As(err, &sizeErr), errors.As(err, &depthErr), errors.As(err, &seqErr):
+			reason = ingresstemplatev1alpha1.ReasonRenderLimitExceeded
+		}
+		return metav1.Condition{
+			Type:    ingresstemplatev1alpha1.ConditionTypeRendered,
+			Status:  metav1.ConditionFalse,
+			Reason:  reason,
+			Message: err.Error(),
+		}
+	}
+	return metav1.Condition{
+		Type:   ingresstemplatev1alpha1.ConditionTypeRendered,
+		Status: metav1.ConditionTrue,
+		Reason: ingresstemplatev1alpha1.ReasonRenderSucceeded,
+	}
+}
+
+// renderError returns where err occurred, or nil when err is not the error of
+// a field. Secret values are hidden from the message and the snippet.
+func renderError(err error, redact *redactor) *ingresstemplatev1alpha1.RenderError {
+	var fieldErr *render.FieldError
+	if !errors.As(err, &fieldErr) {
+		return nil
+	}
+	return &ingresstemplatev1alpha1.RenderError{
+		Path:    fieldErr.Path,
+		Line:    fieldErr.Line,
+		Column:  fieldErr.Column,
+		Snippet: redact.String(fieldErr.Snippet),
+		Message: redact.String(fieldErr.Err.Error()),
+	}
+}
+
+// Annotations of the Events of render errors, locating the error for tools
+// that do not parse the message.
+const (
+	eventAnnotationPath   = "ingress-template.takumakume.github.io/path"
+	eventAnnotationLine   = "ingress-template.takumakume.github.io/line"
+	eventAnnotationColumn = "ingress-template.takumakume.github.io/column"
+)
+
+func renderErrorAnnotations(renderErr *ingresstemplatev1alpha1.RenderError) map[string]string {
+	if renderErr == nil {
+		return nil
+	}
+	annotations := map[string]string{eventAnnotationPath: renderErr.Path}
+	if renderErr.Line > 0 {
+		annotations[eventAnnotationLine] = strconv.Itoa(renderErr.Line)
+	}
+	if renderErr.Column > 0 {
+		annotations[eventAnnotationColumn] = strconv.Itoa(renderErr.Column)
+	}
+	return annotations
+}
@@ -0,0 +1,322 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ingresstemplatev1alpha1 "github.com/takumakume/ingress-template-operator/api/v1alpha1"
+	"github.com/takumakume/ingress-template-operator/pkg/render"
+)
+
+func TestIngressTemplateReconciler_Reconcile_status(t *testing.T) {
+	ingresstemplate := &ingresstemplatev1alpha1.IngressTemplate{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "app", Generation: 1},
+		Spec: ingresstemplatev1alpha1.IngressTemplateSpec{
+			IngressSpecTemplate: ingresstemplatev1alpha1.IngressSpecTemplate{
+				Rules: []ingresstemplatev1alpha1.IngressRuleTemplate{{Host: "{{ .Metadata.Name }}.example.com"}},
+			},
+		},
+	}
+	r, c, _ := newFakeReconciler(t,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns"}},
+		ingresstemplate,
+	)
+	r.ForceConflicts = true
+	key := client.ObjectKeyFromObject(ingresstemplate)
+
+	reconcile := func() *ingresstemplatev1alpha1.IngressTemplate {
+		t.Helper()
+		_, _ = r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key})
+		got := &ingresstemplatev1alpha1.IngressTemplate{}
+		if err := c.Get(context.Background(), key, got); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+	wantConditions := func(got *ingresstemplatev1alpha1.IngressTemplate, want map[string]metav1.ConditionStatus) {
+		t.Helper()
+		for typ, status := range want {
+			cond := meta.FindStatusCondition(got.Status.Conditions, typ)
+			if cond == nil || cond.Status != status {
+				t.Errorf("condition %s = %+v, want status %s", typ, cond, status)
+				continue
+			}
+			if cond.ObservedGeneration != got.Generation {
+				t.Errorf("condition %s observedGeneration = %d, want %d", typ, cond.ObservedGeneration, got.Generation)
+			}
+		}
+	}
+
+	got := reconcile()
+	wantConditions(got, map[string]metav1.ConditionStatus{
+		ingresstemplatev1alpha1.ConditionTypeRendered: metav1.ConditionTrue,
+		ingresstemplatev1alpha1.ConditionTypeApplied:  metav1.ConditionTrue,
+		ingresstemplatev1alpha1.ConditionTypeReady:    metav1.ConditionTrue,
+		ingresstemplatev1alpha1.ConditionTypeDegraded: metav1.ConditionFalse,
+	})
+	if got.Status.Ready != corev1.ConditionTrue || got.Status.ObservedGeneration != 1 {
+		t.Errorf("status = %+v, want ready at generation 1", got.Status)
+	}
+	if got.Status.IngressRef == nil || got.Status.IngressRef.Name != "app" {
+		t.Errorf("status.ingressRef = %+v, want app", got.Status.IngressRef)
+	}
+	ingress := &networkingv1.Ingress{}
+	if err := c.Get(context.Background(), key, ingress); err != nil {
+		t.Fatalf("Ingress was not created: %v", err)
+	}
+	patchOpts := &client.PatchOptions{}
+	patchOpts.ApplyOptions(c.opts)
+	if patchOpts.FieldManager != fieldManager || patchOpts.Force == nil || !*patchOpts.Force {
+		t.Errorf("Ingress applied with %+v, want field manager %s forcing conflicts", patchOpts, fieldManager)
+	}
+
+	got.Spec.IngressSpecTemplate.Rules[0].Host = "{{ .Metadata.Name"
+	got.Generation = 2
+	if err := c.Update(context.Background(), got); err != nil {
+		t.Fatal(err)
+	}
+	got = reconcile()
+	wantConditions(got, map[string]metav1.ConditionStatus{
+		ingresstemplatev1alpha1.ConditionTypeRendered: metav1.ConditionFalse,
+		ingresstemplatev1alpha1.ConditionTypeApplied:  metav1.ConditionFalse,
+		ingresstemplatev1alpha1.ConditionTypeReady:    metav1.ConditionFalse,
+		ingresstemplatev1alpha1.ConditionTypeDegraded: metav1.ConditionTrue,
+	})
+	if got.Status.Ready != corev1.ConditionFalse || got.Status.ObservedGeneration != 2 {
+		t.Errorf("status = %+v, want not ready at generation 2", got.Status)
+	}
+	if got.Status.IngressRef == nil {
+		t.Errorf("status.ingressRef is unset, want the Ingress still in place")
+	}
+}
+
+func Test_appliedCondition(t *testing.T) {
+	gr := schema.GroupResource{Group: "networking.k8s.io", Resource: "ingresses"}
+	tests := []struct {
+		name       string
+		err        error
+		want       metav1.ConditionStatus
+		wantReason string
+	}{
+		{name: "applied", want: metav1.ConditionTrue, wantReason: ingresstemplatev1alpha1.ReasonApplySucceeded},
+		{name: "conflict", err: apierrors.NewConflict(gr, "app", errors.New(`conflict with "kubectl": .spec.rules`)), want: metav1.ConditionFalse, wantReason: ingresstemplatev1alpha1.ReasonApplyConflict},
+		{name: "failed", err: apierrors.NewForbidden(gr, "app", errors.New("denied")), want: metav1.ConditionFalse, wantReason: ingresstemplatev1alpha1.ReasonApplyFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := appliedCondition(tt.err)
+			if got.Status != tt.want || got.Reason != tt.wantReason {
+				t.Errorf("appliedCondition() = %s/%s, want %s/%s", got.Status, got.Reason, tt.want, tt.wantReason)
+			}
+		})
+	}
+}
+
+func Test_ingressApplyConfiguration(t *testing.T) {
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "app", Labels: map[string]string{"app": "app"}},
+		Spec: networkingv1.IngressSpec{
+			Rules: []networkingv1.IngressRule{{Host: "app.example.com"}},
+		},
+	}
+	got, err := ingressApplyConfiguration(ingress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.GetAPIVersion() != "networking.k8s.io/v1" || got.GetKind() != "Ingress" {
+		t.Errorf("ingressApplyConfiguration() is a %s %s, want a networking.k8s.io/v1 Ingress", got.GetAPIVersion(), got.GetKind())
+	}
+	for _, field := range [][]string{{"status"}, {"metadata", "creationTimestamp"}} {
+		if _, found, _ := unstructured.NestedFieldNoCopy(got.Object, field...); found {
+			t.Errorf("ingressApplyConfiguration() sets %v", field)
+		}
+	}
+	if got.GetLabels()["app"] != "app" {
+		t.Errorf("ingressApplyConfiguration() labels = %v, want the labels of the Ingress", got.GetLabels())
+	}
+	if ingress.Kind != "" {
+		t.Errorf("ingressApplyConfiguration() modified its argument")
+	}
+}
+
+func Test_degradedCondition(t *testing.T) {
+	failed := metav1.Condition{Status: metav1.ConditionFalse, Reason: ingresstemplatev1alpha1.ReasonRenderFailed, Message: "boom"}
+	tests := []struct {
+		name       string
+		ready      metav1.Condition
+		hasIngress bool
+		want       metav1.ConditionStatus
+		wantReason string
+	}{
+		{name: "ready", ready: metav1.Condition{Status: metav1.ConditionTrue}, hasIngress: true, want: metav1.ConditionFalse, wantReason: ingresstemplatev1alpha1.ReasonReconciled},
+		{name: "failed with an Ingress", ready: failed, hasIngress: true, want: metav1.ConditionTrue, wantReason: ingresstemplatev1alpha1.ReasonRenderFailed},
+		{name: "failed without an Ingress", ready: failed, want: metav1.ConditionFalse, wantReason: ingresstemplatev1alpha1.ReasonIngressNotCreated},
+		{name: "observing only", ready: metav1.Condition{Status: metav1.ConditionFalse, Reason: ingresstemplatev1alpha1.ReasonObserveOnly}, hasIngress: true, want: metav1.ConditionFalse, wantReason: ingresstemplatev1alpha1.ReasonObserveOnly},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := degradedCondition(tt.ready, tt.hasIngress)
+			if got.Status != tt.want || got.Reason != tt.wantReason {
+				t.Errorf("degradedCondition() = %s/%s, want %s/%s", got.Status, got.Reason, tt.want, tt.wantReason)
+			}
+		})
+	}
+}
+
+// applyClient emulates server-side apply, which the fake client does not
+// support, with a create or an update of the applied object.
+type applyClient struct {
+	client.Client
+
+	// opts are the options of the last apply.
+	opts []client.PatchOption
+}
+
+func (c *applyClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch.Type() != types.ApplyPatchType {
+		return c.Client.Patch(ctx, obj, patch, opts...)
+	}
+	c.opts = opts
+	u := obj.(*unstructured.Unstructured)
+	typed, err := c.Scheme().New(u.GroupVersionKind())
+	if err != nil {
+		return err
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, typed); err != nil {
+		return err
+	}
+	applied := typed.(client.Object)
+	current := applied.DeepCopyObject().(client.Object)
+	err = c.Get(ctx, client.ObjectKeyFromObject(applied), current)
+	switch {
+	case apierrors.IsNotFound(err):
+		err = c.Create(ctx, applied)
+	case err == nil:
+		applied.SetResourceVersion(current.GetResourceVersion())
+		err = c.Update(ctx, applied)
+	}
+	if err != nil {
+		return err
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(applied)
+	if err != nil {
+		return err
+	}
+	u.Object = content
+	return nil
+}
+
+// newFakeReconciler returns a reconciler for a fake client with objs, which
+// emulates server-side apply, and the recorder of its events.
+func newFakeReconciler(t *testing.T, objs ...client.Object) (*IngressTemplateReconciler, *applyClient, *record.FakeRecorder) {
+	t.Helper()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := ingresstemplatev1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	c := &applyClient{Client: fake.NewClientBuilder().WithScheme(s).WithObjects(objs...).Build()}
+	recorder := record.NewFakeRecorder(10)
+	return &IngressTemplateReconciler{Client: c, Scheme: s, Recorder: recorder}, c, recorder
+}
+
+func TestIngressTemplateReconciler_Reconcile_timeout(t *testing.T) {
+	ingresstemplate := &ingresstemplatev1alpha1.IngressTemplate{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "timeout", Name: "app", Generation: 1},
+		Spec: ingresstemplatev1alpha1.IngressTemplateSpec{
+			IngressSpecTemplate: ingresstemplatev1alpha1.IngressSpecTemplate{
+				Rules: []ingresstemplatev1alpha1.IngressRuleTemplate{{Host: "{{ $l := until 100000 }}{{ range $l }}{{ range $l }}{{ end }}{{ end }}"}},
+			},
+		},
+	}
+	r, c, recorder := newFakeReconciler(t,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "timeout"}},
+		ingresstemplate,
+	)
+	r.Limits = render.Limits{Timeout: 20 * time.Millisecond}
+	key := client.ObjectKeyFromObject(ingresstemplate)
+
+	reconcile := func() time.Duration {
+		t.Helper()
+		result, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key})
+		if err != nil {
+			t.Fatalf("Reconcile() error = %v, want the timed out render retried after a delay", err)
+		}
+		return result.RequeueAfter
+	}
+
+	if got := reconcile(); got != timeoutRetryBase {
+		t.Errorf("requeue after the first timeout = %v, want %v", got, timeoutRetryBase)
+	}
+	if got := reconcile(); got <= 0 || got > timeoutRetryBase {
+		t.Errorf("requeue before the retry = %v, want the rest of the delay", got)
+	}
+	if len(recorder.Events) != 1 {
+		t.Errorf("recorded %d events, want the render not retried before the delay", len(recorder.Events))
+	}
+
+	v, _ := r.timedOut.Load(key.String())
+	retry := v.(timeoutRetry)
+	retry.at = time.Now()
+	r.timedOut.Store(key.String(), retry)
+	if got := reconcile(); got != 2*timeoutRetryBase {
+		t.Errorf("requeue after the second timeout = %v, want %v", got, 2*timeoutRetryBase)
+	}
+	if len(recorder.Events) != 2 {
+		t.Errorf("recorded %d events, want the render retried after the delay", len(recorder.Events))
+	}
+
+	got := &ingresstemplatev1alpha1.IngressTemplate{}
+	if err := c.Get(context.Background(), key, got); err != nil {
+		t.Fatal(err)
+	}
+	rendered := meta.FindStatusCondition(got.Status.Conditions, ingresstemplatev1alpha1.ConditionTypeRendered)
+	if rendered == nil || rendered.Reason != ingresstemplatev1alpha1.ReasonRenderLimitExceeded {
+		t.Errorf("Rendered condition = %+v, want reason RenderLimitExceeded", rendered)
+	}
+
+	got.Spec.IngressSpecTemplate.Rules[0].Host = "app.example.com"
+	got.Generation = 2
+	if err := c.Update(context.Background(), got); err != nil {
+		t.Fatal(err)
+	}
+	reconcile()
+	if err := c.Get(context.Background(), key, &networkingv1.Ingress{}); err != nil {
+		t.Errorf("Ingress was not created once the IngressTemplate changed: %v", err)
+	}
+}
@@ -18,13 +18,17 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -33,6 +37,7 @@ import (
 
 	ingresstemplatev1alpha1 "github.com/takumakume/ingress-template-operator/api/v1alpha1"
 	"github.com/takumakume/ingress-template-operator/controllers"
+	"github.com/takumakume/ingress-template-operator/pkg/render"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -52,11 +57,61 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var strictMissingKeys bool
+	var enableWebhooks bool
+	var lookupKinds string
+	var templateCacheSize int
+	var forceConflicts bool
+	var observeOnly bool
+	var ignoreJSONPointers, ignoreLabels, ignoreAnnotations string
+	var limits render.Limits
+	var lookup controllers.LookupConfig
+	var cluster render.Cluster
+	clusterValues := keyValueFlag{}
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&strictMissingKeys, "strict-missing-keys", false,
+		"Fail rendering when a template refers to a missing key instead of rendering \"<no value>\". "+
+			"IngressTemplates can override this with spec.strictMissingKeys.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false,
+		"Serve the validating admission webhook for IngressTemplates. "+
+			"Requires a serving certificate and the webhook configuration in config/webhook.")
+	flag.StringVar(&cluster.Name, "cluster-name", "", "The name of the cluster, available to templates as .Cluster.Name.")
+	flag.StringVar(&cluster.BaseDomain, "base-domain", "", "The base domain of the cluster, available to templates as .Cluster.BaseDomain.")
+	flag.Var(clusterValues, "cluster-value",
+		"A key=value pair available to templates as .Cluster.Values.<key>. Can be given multiple times.")
+	flag.StringVar(&lookupKinds, "lookup-kinds", "Service,Deployment.apps",
+		"Comma separated kinds, as Kind.group, that templates can read with the lookup function. "+
+			"Add ConfigMap or Secret to let templates read them. "+
+			"The operator needs RBAC permissions to get, list and watch them.")
+	flag.BoolVar(&lookup.AllNamespaces, "lookup-all-namespaces", false,
+		"Allow the lookup function to read objects outside the namespace of the IngressTemplate.")
+	flag.IntVar(&templateCacheSize, "template-cache-size", 1000,
+		"The number of IngressTemplates whose compiled templates are kept between reconciles. "+
+			"0 disables the cache.")
+	flag.BoolVar(&forceConflicts, "apply-force-conflicts", false,
+		"Take over fields of generated Ingresses that another field manager changed, correcting the drift. "+
+			"Otherwise such conflicts fail the apply and are reported with the ApplyConflict reason.")
+	flag.BoolVar(&observeOnly, "observe-only", false,
+		"Render Ingresses and report how the Ingresses in the cluster differ from them, without writing them. "+
+			"IngressTemplates can override this with spec.observeOnly.")
+	flag.StringVar(&ignoreJSONPointers, "ignore-json-pointers", "",
+		"Comma separated JSON pointers, such as /spec/defaultBackend, to fields that are left as they are in every Ingress once it exists.")
+	flag.StringVar(&ignoreLabels, "ignore-labels", "",
+		"Comma separated globs of label keys that are left as they are in every Ingress once it exists.")
+	flag.StringVar(&ignoreAnnotations, "ignore-annotations", "",
+		"Comma separated globs of annotation keys, such as cert-manager.io/*, that are left as they are in every Ingress once it exists.")
+	flag.DurationVar(&limits.Timeout, "render-timeout", 5*time.Second,
+		"The time rendering an IngressTemplate may take. 0 disables the limit.")
+	flag.IntVar(&limits.MaxFieldSize, "render-max-field-size", 256*1024,
+		"The size in bytes a single rendered field may have. 0 disables the limit.")
+	flag.IntVar(&limits.MaxObjectSize, "render-max-ingress-size", 1024*1024,
+		"The size in bytes all rendered fields of an Ingress may have together. 0 disables the limit.")
+	flag.IntVar(&limits.MaxDepth, "render-max-depth", 100,
+		"How deep template and include calls may be nested. 0 disables the limit.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -64,6 +119,18 @@ func main() {
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+	cluster.Values = clusterValues
+	for _, kind := range strings.Split(lookupKinds, ",") {
+		if kind = strings.TrimSpace(kind); kind != "" {
+			lookup.Kinds = append(lookup.Kinds, schema.ParseGroupKind(kind))
+		}
+	}
+	var ignore ingresstemplatev1alpha1.IgnoreDifferences
+	for _, pointer := range splitList(ignoreJSONPointers) {
+		ignore.JSONPointers = append(ignore.JSONPointers, ingresstemplatev1alpha1.JSONPointer(pointer))
+	}
+	ignore.Labels = splitList(ignoreLabels)
+	ignore.Annotations = splitList(ignoreAnnotations)
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
@@ -89,13 +156,33 @@ func main() {
 		os.Exit(1)
 	}
 
+	var templateCache *render.Cache
+	if templateCacheSize > 0 {
+		templateCache = render.NewCache(templateCacheSize)
+	}
+
 	if err = (&controllers.IngressTemplateReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:            mgr.GetClient(),
+		Scheme:            mgr.GetScheme(),
+		Recorder:          mgr.GetEventRecorderFor("ingress-template-controller"),
+		StrictMissingKeys: strictMissingKeys,
+		Cluster:           cluster,
+		Lookup:            lookup,
+		Limits:            limits,
+		Cache:             templateCache,
+		ForceConflicts:    forceConflicts,
+		IgnoreDifferences: ignore,
+		ObserveOnly:       observeOnly,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "IngressTemplate")
 		os.Exit(1)
 	}
+	if enableWebhooks {
+		if err = (&ingresstemplatev1alpha1.IngressTemplate{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "IngressTemplate")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -113,3 +200,34 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// splitList returns the non-empty elements of the comma separated list s.
+func splitList(s string) []string {
+	var list []string
+	for _, elem := range strings.Split(s, ",") {
+		if elem = strings.TrimSpace(elem); elem != "" {
+			list = append(list, elem)
+		}
+	}
+	return list
+}
+
+// keyValueFlag is a flag that can be given multiple times as key=value.
+type keyValueFlag map[string]string
+
+func (f keyValueFlag) String() string {
+	pairs := make([]string, 0, len(f))
+	for k, v := range f {
+		pairs = append(pairs, k+"="+v)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (f keyValueFlag) Set(value string) error {
+	k, v, ok := strings.Cut(value, "=")
+	if !ok || k == "" {
+		return fmt.Errorf("%q is not a key=value pair", value)
+	}
+	f[k] = v
+	return nil
+}
@@ -0,0 +1,86 @@
+// Package parameters validates the parameters of an IngressTemplate against the
+// schema declared by the template and applies the schema's defaults.
+package parameters
+
+import (
+	"fmt"
+
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	structuralschema "k8s.io/apiextensions-apiserver/pkg/apiserver/schema"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/schema/defaulting"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/validation"
+	"k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+var (
+	parametersPath = field.NewPath("spec", "parameters")
+	schemaPath     = field.NewPath("spec", "parametersSchema")
+)
+
+// Error reports that the parameters or the schema of an IngressTemplate are invalid.
+type Error struct {
+	Errs field.ErrorList
+}
+
+func (e *Error) Error() string {
+	return e.Errs.ToAggregate().Error()
+}
+
+// Resolve returns values with the defaults of schema applied, after checking
+// them against schema. Both schema and values are optional: without a schema
+// the values are returned as they are.
+func Resolve(schema, values *apiextensionsv1.JSON) (map[string]interface{}, error) {
+	obj := map[string]interface{}{}
+	if values != nil && len(values.Raw) > 0 {
+		if err := json.Unmarshal(values.Raw, &obj); err != nil {
+			return nil, &Error{Errs: field.ErrorList{field.Invalid(parametersPath, field.OmitValueType{}, fmt.Sprintf("must be an object: %s", err))}}
+		}
+	}
+
+	if schema == nil || len(schema.Raw) == 0 {
+		return obj, nil
+	}
+
+	props, structural, err := parseSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	defaulting.Default(obj, structural)
+
+	validator, _, err := validation.NewSchemaValidator(&apiextensions.CustomResourceValidation{OpenAPIV3Schema: props})
+	if err != nil {
+		return nil, &Error{Errs: field.ErrorList{field.Invalid(schemaPath, field.OmitValueType{}, err.Error())}}
+	}
+	if errs := validation.ValidateCustomResource(parametersPath, obj, validator); len(errs) > 0 {
+		return nil, &Error{Errs: errs}
+	}
+
+	return obj, nil
+}
+
+// parseSchema decodes schema and checks that it is a structural schema,
+// the same requirement the API server has for the schema of a CRD.
+func parseSchema(schema *apiextensionsv1.JSON) (*apiextensions.JSONSchemaProps, *structuralschema.Structural, error) {
+	v1Props := &apiextensionsv1.JSONSchemaProps{}
+	if err := json.Unmarshal(schema.Raw, v1Props); err != nil {
+		return nil, nil, &Error{Errs: field.ErrorList{field.Invalid(schemaPath, field.OmitValueType{}, err.Error())}}
+	}
+
+	props := &apiextensions.JSONSchemaProps{}
+	if err := apiextensionsv1.Convert_v1_JSONSchemaProps_To_apiextensions_JSONSchemaProps(v1Props, props, nil); err != nil {
+		return nil, nil, &Error{Errs: field.ErrorList{field.Invalid(schemaPath, field.OmitValueType{}, err.Error())}}
+	}
+
+	structural, err := structuralschema.NewStructural(props)
+	if err != nil {
+		return nil, nil, &Error{Errs: field.ErrorList{field.Invalid(schemaPath, field.OmitValueType{}, err.Error())}}
+	}
+	if errs := structuralschema.ValidateStructural(schemaPath, structural); len(errs) > 0 {
+		return nil, nil, &Error{Errs: errs}
+	}
+
+	return props, structural, nil
+}
@@ -0,0 +1,114 @@
+package parameters
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+func TestResolve(t *testing.T) {
+	schema := &apiextensionsv1.JSON{Raw: []byte(`{
+		"type": "object",
+		"required": ["service"],
+		"properties": {
+			"service": {"type": "string"},
+			"port": {"type": "integer", "default": 80},
+			"tls": {"type": "boolean"}
+		}
+	}`)}
+
+	type args struct {
+		schema *apiextensionsv1.JSON
+		values *apiextensionsv1.JSON
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    map[string]interface{}
+		wantErr string
+	}{
+		{
+			name: "no schema and no values",
+			args: args{},
+			want: map[string]interface{}{},
+		},
+		{
+			name: "no schema",
+			args: args{
+				values: &apiextensionsv1.JSON{Raw: []byte(`{"service":"app"}`)},
+			},
+			want: map[string]interface{}{"service": "app"},
+		},
+		{
+			name: "defaults",
+			args: args{
+				schema: schema,
+				values: &apiextensionsv1.JSON{Raw: []byte(`{"service":"app"}`)},
+			},
+			want: map[string]interface{}{"service": "app", "port": int64(80)},
+		},
+		{
+			name: "set value overrides default",
+			args: args{
+				schema: schema,
+				values: &apiextensionsv1.JSON{Raw: []byte(`{"service":"app","port":8080}`)},
+			},
+			want: map[string]interface{}{"service": "app", "port": int64(8080)},
+		},
+		{
+			name: "missing required",
+			args: args{
+				schema: schema,
+			},
+			wantErr: `spec.parameters.service: Required value`,
+		},
+		{
+			name: "wrong type",
+			args: args{
+				schema: schema,
+				values: &apiextensionsv1.JSON{Raw: []byte(`{"service":"app","tls":"yes"}`)},
+			},
+			wantErr: `spec.parameters.tls: Invalid value: "string": tls in body must be of type boolean`,
+		},
+		{
+			name: "values are not an object",
+			args: args{
+				values: &apiextensionsv1.JSON{Raw: []byte(`["app"]`)},
+			},
+			wantErr: `spec.parameters: Invalid value: must be an object`,
+		},
+		{
+			name: "schema is not structural",
+			args: args{
+				schema: &apiextensionsv1.JSON{Raw: []byte(`{"type":"object","properties":{"service":{}}}`)},
+			},
+			wantErr: `spec.parametersSchema.properties[service].type: Required value`,
+		},
+		{
+			name: "schema root is not an object",
+			args: args{
+				schema: &apiextensionsv1.JSON{Raw: []byte(`{"type":"string"}`)},
+			},
+			wantErr: `spec.parametersSchema.type: Invalid value: "string": must be object at the root`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Resolve(tt.args.schema, tt.args.values)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Resolve() error = %v, wantErr %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Resolve() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package render
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Cache keeps the compiled templates of IngressTemplates between renders, so
+// that a template string is only parsed again when its IngressTemplate, or a
+// library it uses, changes. Each engine decides what it keeps in the cache.
+// When the cache is full, the least recently used IngressTemplate is evicted.
+// A Cache is safe for concurrent use.
+type Cache struct {
+	size int
+
+	mu    sync.Mutex
+	order *list.List
+	items map[string]*list.Element
+}
+
+// NewCache returns a Cache holding the compiled templates of at most size IngressTemplates.
+func NewCache(size int) *Cache {
+	return &Cache{
+		size:  size,
+		order: list.New(),
+		items: map[string]*list.Element{},
+	}
+}
+
+// Len returns the number of IngressTemplates in the cache.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Delete removes the compiled templates stored under key.
+func (c *Cache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[key]; ok {
+		c.order.Remove(e)
+		delete(c.items, key)
+	}
+}
+
+type cacheEntry struct {
+	key     string
+	version string
+	value   interface{}
+}
+
+// get returns the compiled templates stored under key, or new ones built by
+// newValue when there are none or they were compiled for another version.
+func (c *Cache) get(key, version string, newValue func() interface{}) interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.items[key]; ok {
+		if entry := e.Value.(*cacheEntry); entry.version == version {
+			c.order.MoveToFront(e)
+			return entry.value
+		}
+		c.order.Remove(e)
+		delete(c.items, key)
+	}
+
+	entry := &cacheEntry{key: key, version: version, value: newValue()}
+	c.items[key] = c.order.PushFront(entry)
+	for c.size > 0 && c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).key)
+	}
+	return entry.value
+}
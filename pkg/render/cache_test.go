@@ -0,0 +1,219 @@
+package render
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCache_get(t *testing.T) {
+	c := NewCache(2)
+	newSet := func() interface{} { return newCompiledSet(defaultFuncs, Delimiters{}, false, nil) }
+
+	a := c.get("a", "1", newSet)
+	if got := c.get("a", "1", newSet); got != a {
+		t.Errorf("Cache.get() returned a new set for the same version")
+	}
+	if got := c.get("a", "2", newSet); got == a {
+		t.Errorf("Cache.get() returned the old set for a new version")
+	}
+	if c.Len() != 1 {
+		t.Errorf("Cache.Len() = %d, want 1", c.Len())
+	}
+
+	a = c.get("a", "2", newSet)
+	c.get("b", "1", newSet)
+	c.get("a", "2", newSet) // a is now more recently used than b
+	c.get("c", "1", newSet)
+	if c.Len() != 2 {
+		t.Errorf("Cache.Len() = %d, want 2", c.Len())
+	}
+	if got := c.get("a", "2", newSet); got != a {
+		t.Errorf("Cache.get() evicted the most recently used set")
+	}
+	if _, ok := c.items["b"]; ok {
+		t.Errorf("Cache.get() did not evict the least recently used set")
+	}
+
+	c.Delete("a")
+	if _, ok := c.items["a"]; ok {
+		t.Errorf("Cache.Delete() did not remove the set")
+	}
+}
+
+func TestRender_cache(t *testing.T) {
+	c := NewCache(10)
+	libraries := []Library{{Name: "lib", Templates: `{{ define "host" }}{{ .Metadata.Name }}.example.com{{ end }}`}}
+
+	render := func(generation int64, libraries []Library) string {
+		ing := &networkingv1.Ingress{
+			Spec: networkingv1.IngressSpec{
+				Rules: []networkingv1.IngressRule{
+					{Host: `{{ template "host" . }}`},
+				},
+			},
+		}
+		got, err := Render(context.Background(), ing, Options{
+			Metadata:  metav1.ObjectMeta{Name: "app", Generation: generation},
+			Libraries: libraries,
+			Cache:     c,
+			CacheKey:  "ns/app",
+		})
+		if err != nil {
+			t.Fatalf("Render() error = %v", err)
+		}
+		return got.Spec.Rules[0].Host
+	}
+
+	if got := render(1, libraries); got != "app.example.com" {
+		t.Errorf("Render() = %v, want app.example.com", got)
+	}
+	set := c.items["ns/app"].Value.(*cacheEntry).value.(*compiledSet)
+	if len(set.fields) != 1 {
+		t.Errorf("compiled %d template strings, want 1", len(set.fields))
+	}
+
+	if got := render(1, libraries); got != "app.example.com" {
+		t.Errorf("Render() = %v, want app.example.com", got)
+	}
+	if c.items["ns/app"].Value.(*cacheEntry).value.(*compiledSet) != set {
+		t.Errorf("Render() compiled the templates again for the same generation")
+	}
+
+	changed := []Library{{Name: "lib", Templates: `{{ define "host" }}{{ .Metadata.Name }}.example.org{{ end }}`}}
+	if got := render(1, changed); got != "app.example.org" {
+		t.Errorf("Render() = %v, want app.example.org after the library changed", got)
+	}
+}
+
+func TestRender_cache_lookup(t *testing.T) {
+	c := NewCache(10)
+	libraries := []Library{{Name: "lib", Templates: `{{ define "port" }}{{ (index (lookup "v1" "Service" "" .).spec.ports 0).name }}{{ end }}`}}
+
+	for _, port := range []string{"http", "https"} {
+		ing := &networkingv1.Ingress{
+			Spec: networkingv1.IngressSpec{
+				Rules: []networkingv1.IngressRule{
+					{Host: `{{ include "port" "app" }}`},
+				},
+			},
+		}
+		got, err := Render(context.Background(), ing, Options{
+			Metadata:  metav1.ObjectMeta{Name: "app", Generation: 1},
+			Libraries: libraries,
+			Lookup: fakeLookuper{
+				"v1/Service//app": {
+					"spec": map[string]interface{}{
+						"ports": []interface{}{map[string]interface{}{"name": port}},
+					},
+				},
+			},
+			Cache:    c,
+			CacheKey: "ns/app",
+		})
+		if err != nil {
+			t.Fatalf("Render() error = %v", err)
+		}
+		if host := got.Spec.Rules[0].Host; host != port {
+			t.Errorf("Render() = %v, want %v from the object looked up in this render", host, port)
+		}
+	}
+}
+
+func Test_renderer_render_constant(t *testing.T) {
+	r := newRenderer(nil)
+	got, err := r.render("no actions here")
+	if err != nil || got != "no actions here" {
+		t.Errorf("renderer.render() = %v, %v", got, err)
+	}
+	if r.eval.(*templateEvaluator).set != nil {
+		t.Errorf("renderer.render() compiled a string without actions")
+	}
+}
+
+// benchmarkIngress returns an Ingress with the templated and constant fields
+// of a typical IngressTemplate.
+func benchmarkIngress() *networkingv1.Ingress {
+	pathType := networkingv1.PathTypePrefix
+	return &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{
+				"app.kubernetes.io/name":       "{{ .Metadata.Name }}",
+				"app.kubernetes.io/managed-by": "ingress-template-operator",
+			},
+			Annotations: map[string]string{
+				"cert-manager.io/cluster-issuer":             "letsencrypt",
+				"nginx.ingress.kubernetes.io/rewrite-target": "/",
+				"example.com/owner":                          "{{ .Metadata.Namespace | upper }}",
+			},
+		},
+		Spec: networkingv1.IngressSpec{
+			TLS: []networkingv1.IngressTLS{
+				{
+					Hosts:      []string{"{{ .Metadata.Name }}.{{ .Metadata.Namespace }}.example.com"},
+					SecretName: "{{ .Metadata.Name }}-tls",
+				},
+			},
+			Rules: []networkingv1.IngressRule{
+				{
+					Host: "{{ .Metadata.Name }}.{{ .Metadata.Namespace }}.example.com",
+					IngressRuleValue: networkingv1.IngressRuleValue{
+						HTTP: &networkingv1.HTTPIngressRuleValue{
+							Paths: []networkingv1.HTTPIngressPath{
+								{
+									Path:     "/",
+									PathType: &pathType,
+									Backend: networkingv1.IngressBackend{
+										Service: &networkingv1.IngressServiceBackend{
+											Name: "{{ .Metadata.Name }}",
+											Port: networkingv1.ServiceBackendPort{Name: "http"},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func BenchmarkRender(b *testing.B) {
+	ing := benchmarkIngress()
+
+	for _, n := range []int{1000, 10000} {
+		names := make([]string, n)
+		for i := range names {
+			names[i] = fmt.Sprintf("app-%d", i)
+		}
+
+		for _, cached := range []bool{false, true} {
+			var cache *Cache
+			name := fmt.Sprintf("%d/uncached", n)
+			if cached {
+				cache = NewCache(n)
+				name = fmt.Sprintf("%d/cached", n)
+			}
+
+			b.Run(name, func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					for _, name := range names {
+						opt := Options{
+							Metadata: metav1.ObjectMeta{Name: name, Namespace: "ns", Generation: 1},
+							Cache:    cache,
+							CacheKey: "ns/" + name,
+						}
+						if _, err := Render(context.Background(), ing.DeepCopy(), opt); err != nil {
+							b.Fatal(err)
+						}
+					}
+				}
+			})
+		}
+	}
+}
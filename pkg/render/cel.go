@@ -0,0 +1,487 @@
+package render
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/ext"
+	"google.golang.org/protobuf/types/known/structpb"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CELEngine evaluates the ${ expr } expressions in the string fields as CEL
+// expressions, with the same values the templates get as variables, such as
+// ${ Metadata.Name + "." + Cluster.BaseDomain }. Write $${ for a literal ${.
+type CELEngine struct{}
+
+func (CELEngine) NewEvaluator(ctx context.Context, opt *Options) Evaluator {
+	return &celEvaluator{
+		ctx:        ctx,
+		limits:     opt.Limits,
+		data:       opt.ToMap(),
+		cache:      opt.Cache,
+		cacheKey:   opt.CacheKey,
+		generation: opt.Metadata.Generation,
+	}
+}
+
+// CELError reports a CEL expression that could not be compiled or evaluated.
+type CELError struct {
+	Expression string
+	Err        error
+
+	// Line and Column are the 1-based position of the error in the string
+	// the expression is part of: that of the compile error, or the start of
+	// the expression for evaluation errors.
+	Line   int
+	Column int
+}
+
+func (e *CELError) Error() string {
+	return fmt.Sprintf("CEL expression %q: %s", e.Expression, e.Err)
+}
+
+func (e *CELError) Unwrap() error {
+	return e.Err
+}
+
+func (e *CELError) location() (int, int) {
+	return e.Line, e.Column
+}
+
+// celCompileError reports the issues of an expression that does not compile.
+// pos is the position of the first issue in the expression.
+type celCompileError struct {
+	msg string
+	pos common.Location
+}
+
+func (e *celCompileError) Error() string {
+	return e.msg
+}
+
+type celEvaluator struct {
+	ctx    context.Context
+	limits Limits
+	data   map[string]interface{}
+
+	// cache shares the compiled programs stored under cacheKey between renders.
+	cache      *Cache
+	cacheKey   string
+	generation int64
+
+	programs *celPrograms
+	vars     map[string]interface{}
+}
+
+func (e *celEvaluator) HasExpression(s string) bool {
+	return strings.Contains(s, "${")
+}
+
+func (e *celEvaluator) Close() {}
+
+func (e *celEvaluator) Evaluate(s string) (string, error) {
+	segments, err := splitExpressions(s)
+	if err != nil {
+		return "", err
+	}
+
+	if e.programs == nil {
+		newPrograms := func() interface{} {
+			return &celPrograms{programs: map[string]*celProgram{}}
+		}
+		if e.cache != nil && e.cacheKey != "" {
+			e.programs = e.cache.get(e.cacheKey, "cel-"+strconv.FormatInt(e.generation, 10), newPrograms).(*celPrograms)
+		} else {
+			e.programs = newPrograms().(*celPrograms)
+		}
+		e.vars = celVariables(e.data)
+	}
+
+	var b strings.Builder
+	for _, seg := range segments {
+		if !seg.expr {
+			b.WriteString(seg.text)
+			continue
+		}
+		out, err := e.eval(seg.text)
+		if err != nil {
+			var celErr *CELError
+			if errors.As(err, &celErr) {
+				celErr.Line, celErr.Column = position(s, seg.offset, celErr.Err)
+			}
+			return "", err
+		}
+		b.WriteString(out)
+	}
+	return b.String(), nil
+}
+
+func (e *celEvaluator) eval(expr string) (string, error) {
+	prg, err := e.programs.compile(expr)
+	if err != nil {
+		return "", &CELError{Expression: expr, Err: err}
+	}
+
+	val, _, err := prg.ContextEval(e.ctx, e.vars)
+	if e.ctx.Err() != nil {
+		return "", contextError(e.ctx, e.limits)
+	}
+	if err != nil {
+		return "", &CELError{Expression: expr, Err: err}
+	}
+
+	out, err := celString(val)
+	if err != nil {
+		return "", &CELError{Expression: expr, Err: err}
+	}
+	return out, nil
+}
+
+// position returns the 1-based line and column in s of err, an error of the
+// expression at offset.
+func position(s string, offset int, err error) (int, int) {
+	var compileErr *celCompileError
+	if errors.As(err, &compileErr) && compileErr.pos.Line() > 0 {
+		line, column := lineColumn(s, offset)
+		if compileErr.pos.Line() == 1 {
+			return line, column + compileErr.pos.Column()
+		}
+		return line + compileErr.pos.Line() - 1, compileErr.pos.Column() + 1
+	}
+	return lineColumn(s, offset)
+}
+
+// lineColumn returns the 1-based line and column of offset in s.
+func lineColumn(s string, offset int) (int, int) {
+	before := s[:offset]
+	line := strings.Count(before, "\n") + 1
+	return line, offset - strings.LastIndex(before, "\n")
+}
+
+// celPrograms holds the compiled programs of the expressions of an IngressTemplate.
+type celPrograms struct {
+	mu       sync.Mutex
+	programs map[string]*celProgram
+}
+
+type celProgram struct {
+	prg cel.Program
+	err error
+}
+
+// compile returns the program of expr, compiling it on first use.
+func (p *celPrograms) compile(expr string) (cel.Program, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if c, ok := p.programs[expr]; ok {
+		return c.prg, c.err
+	}
+
+	c := &celProgram{}
+	c.prg, c.err = compileCEL(expr)
+	p.programs[expr] = c
+	return c.prg, c.err
+}
+
+func compileCEL(expr string) (cel.Program, error) {
+	env, err := celEnv()
+	if err != nil {
+		return nil, err
+	}
+	ast, iss := env.Compile(expr)
+	if iss != nil && iss.Err() != nil {
+		errs := iss.Errors()
+		msgs := make([]string, 0, len(errs))
+		for _, e := range errs {
+			msgs = append(msgs, e.Message)
+		}
+		return nil, &celCompileError{msg: strings.Join(msgs, "; "), pos: errs[0].Location}
+	}
+	return env.Program(ast, cel.InterruptCheckFrequency(100))
+}
+
+var (
+	celEnvOnce sync.Once
+	celEnvVal  *cel.Env
+	celEnvErr  error
+)
+
+// celEnv returns the environment expressions are compiled in. It declares the
+// values available to templates as variables, and the DNS functions available
+// to templates.
+func celEnv() (*cel.Env, error) {
+	celEnvOnce.Do(func() {
+		opts := []cel.EnvOption{
+			ext.Strings(),
+			ext.Encoders(),
+			celStringFunc("sanitizeLabel", sanitizeLabel),
+			celStringFunc("dnsLabel", dnsLabel),
+			cel.Function("punycode",
+				cel.Overload("punycode_string", []*cel.Type{cel.StringType}, cel.StringType,
+					cel.UnaryBinding(func(v ref.Val) ref.Val {
+						out, err := punycode(string(v.(types.String)))
+						if err != nil {
+							return types.NewErr("%s", err)
+						}
+						return types.String(out)
+					}))),
+			cel.Function("truncateLabel",
+				cel.Overload("truncateLabel_int_string", []*cel.Type{cel.IntType, cel.StringType}, cel.StringType,
+					cel.BinaryBinding(func(max, s ref.Val) ref.Val {
+						out, err := truncateLabel(int(max.(types.Int)), string(s.(types.String)))
+						if err != nil {
+							return types.NewErr("%s", err)
+						}
+						return types.String(out)
+					}))),
+		}
+		for name := range (&Options{}).ToMap() {
+			opts = append(opts, cel.Variable(name, cel.DynType))
+		}
+		// only set by RenderHosts
+		opts = append(opts, cel.Variable(hostVar, cel.DynType))
+		celEnvVal, celEnvErr = cel.NewEnv(opts...)
+	})
+	return celEnvVal, celEnvErr
+}
+
+func celStringFunc(name string, fn func(string) string) cel.EnvOption {
+	return cel.Function(name,
+		cel.Overload(name+"_string", []*cel.Type{cel.StringType}, cel.StringType,
+			cel.UnaryBinding(func(v ref.Val) ref.Val {
+				return types.String(fn(string(v.(types.String))))
+			})))
+}
+
+// celString returns the result of an expression as it is put into a string
+// field. Lists and maps are encoded as JSON, which can be used in
+// spec.ingressSpecTemplateYAML.
+func celString(val ref.Val) (string, error) {
+	switch v := val.(type) {
+	case types.String:
+		return string(v), nil
+	case types.Int:
+		return strconv.FormatInt(int64(v), 10), nil
+	case types.Uint:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case types.Double:
+		return strconv.FormatFloat(float64(v), 'g', -1, 64), nil
+	case types.Bool:
+		return strconv.FormatBool(bool(v)), nil
+	case types.Bytes:
+		return string(v), nil
+	case types.Null:
+		return "", nil
+	case types.Timestamp:
+		return v.Time.Format(time.RFC3339), nil
+	case types.Duration:
+		return v.Duration.String(), nil
+	}
+
+	native, err := val.ConvertToNative(reflect.TypeOf(&structpb.Value{}))
+	if err != nil {
+		return "", fmt.Errorf("result of type %s can not be put into a string field", val.Type().TypeName())
+	}
+	out, err := json.Marshal(native.(*structpb.Value).AsInterface())
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// celVariables converts the values available to templates into values CEL can
+// use. Structs become maps by field name, so that ${ Metadata.Name } reads the
+// same value as {{ .Metadata.Name }}.
+func celVariables(data map[string]interface{}) map[string]interface{} {
+	vars := make(map[string]interface{}, len(data))
+	for name, value := range data {
+		vars[name] = celValue(reflect.ValueOf(value))
+	}
+	return vars
+}
+
+var (
+	timeType          = reflect.TypeOf(metav1.Time{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+func celValue(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+
+	switch {
+	case v.Type() == timeType:
+		return v.Interface().(metav1.Time).Time
+	case v.Kind() == reflect.Struct && v.Type().Implements(jsonMarshalerType):
+		// types such as apiextensionsv1.JSON are used as the value they encode
+		raw, err := json.Marshal(v.Interface())
+		if err != nil {
+			return nil
+		}
+		var out interface{}
+		if err := json.Unmarshal(raw, &out); err != nil {
+			return nil
+		}
+		return out
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return celValue(v.Elem())
+
+	case reflect.Struct:
+		out := map[string]interface{}{}
+		celStructFields(out, v)
+		return out
+
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		out := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out[fmt.Sprint(iter.Key().Interface())] = celValue(iter.Value())
+		}
+		return out
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Bytes()
+		}
+		out := make([]interface{}, v.Len())
+		for i := range out {
+			out[i] = celValue(v.Index(i))
+		}
+		return out
+
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint()
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	}
+	return nil
+}
+
+// celStructFields adds the exported fields of v to out, including those of
+// embedded structs, which templates can use as if they were fields of v.
+func celStructFields(out map[string]interface{}, v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			celStructFields(out, v.Field(i))
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		out[f.Name] = celValue(v.Field(i))
+	}
+}
+
+// segment is literal text, or the text of an expression when expr is set.
+// offset is the position of an expression in the string it was split from.
+type segment struct {
+	text   string
+	expr   bool
+	offset int
+}
+
+// splitExpressions splits s into literal text and the ${ } expressions in it.
+// Braces and quotes inside an expression are matched, so an expression can
+// contain map literals and strings with "}".
+func splitExpressions(s string) ([]segment, error) {
+	var segments []segment
+	var text strings.Builder
+	pos := 0
+	for {
+		i := strings.Index(s[pos:], "${")
+		if i < 0 {
+			text.WriteString(s[pos:])
+			break
+		}
+		i += pos
+		if i > 0 && s[i-1] == '$' {
+			// $${ is a literal ${
+			text.WriteString(s[pos : i-1])
+			text.WriteString("${")
+			pos = i + 2
+			continue
+		}
+		text.WriteString(s[pos:i])
+
+		end, err := expressionEnd(s[i+2:])
+		if err != nil {
+			return nil, err
+		}
+		if text.Len() > 0 {
+			segments = append(segments, segment{text: text.String()})
+			text.Reset()
+		}
+		body := s[i+2 : i+2+end]
+		expr := strings.TrimSpace(body)
+		if expr == "" {
+			return nil, fmt.Errorf("empty expression at offset %d", i)
+		}
+		offset := i + 2 + len(body) - len(strings.TrimLeft(body, " \t\r\n"))
+		segments = append(segments, segment{text: expr, expr: true, offset: offset})
+		pos = i + 2 + end + 1
+	}
+	if text.Len() > 0 {
+		segments = append(segments, segment{text: text.String()})
+	}
+	return segments, nil
+}
+
+// expressionEnd returns the index of the } that closes the expression s starts.
+func expressionEnd(s string) (int, error) {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return i, nil
+			}
+			depth--
+		case '"', '\'':
+			raw := i > 0 && (s[i-1] == 'r' || s[i-1] == 'R')
+			j := i + 1
+			for ; j < len(s) && s[j] != c; j++ {
+				if s[j] == '\\' && !raw {
+					j++
+				}
+			}
+			if j >= len(s) {
+				return 0, fmt.Errorf("unterminated string in expression %q", "${"+s)
+			}
+			i = j
+		}
+	}
+	return 0, fmt.Errorf("unterminated expression %q", "${"+s)
+}
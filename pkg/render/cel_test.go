@@ -0,0 +1,234 @@
+package render
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_splitExpressions(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    []segment
+		wantErr bool
+	}{
+		{
+			name: "text only",
+			s:    "app.example.com",
+			want: []segment{{text: "app.example.com"}},
+		},
+		{
+			name: "expressions and text",
+			s:    "${ Metadata.Name }.${Cluster.BaseDomain}",
+			want: []segment{{text: "Metadata.Name", expr: true, offset: 3}, {text: "."}, {text: "Cluster.BaseDomain", expr: true, offset: 21}},
+		},
+		{
+			name: "braces and quotes in expressions",
+			s:    `${ {"a": "}"}["a"] }!`,
+			want: []segment{{text: `{"a": "}"}["a"]`, expr: true, offset: 3}, {text: "!"}},
+		},
+		{
+			name: "escaped",
+			s:    "$${ HOME } ${ 1 }",
+			want: []segment{{text: "${ HOME } "}, {text: "1", expr: true, offset: 14}},
+		},
+		{
+			name:    "unterminated expression",
+			s:       "${ Metadata.Name ",
+			wantErr: true,
+		},
+		{
+			name:    "unterminated string",
+			s:       `${ "} }`,
+			wantErr: true,
+		},
+		{
+			name:    "empty expression",
+			s:       "${ }",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitExpressions(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("splitExpressions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitExpressions() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_celEvaluator_Evaluate(t *testing.T) {
+	opt := &Options{
+		Metadata: metav1.ObjectMeta{
+			Name:      "app",
+			Namespace: "team-a",
+			Labels:    map[string]string{"tier": "web"},
+		},
+		Cluster:    Cluster{BaseDomain: "example.com"},
+		ConfigMaps: map[string]map[string]string{"settings": {"port": "8080"}},
+		Values:     map[string]interface{}{"replicas": int64(3), "hosts": []interface{}{"a", "b"}},
+		Spec: struct {
+			Parameters *apiextensionsv1.JSON
+		}{
+			Parameters: &apiextensionsv1.JSON{Raw: []byte(`{"tls":true}`)},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		s          string
+		want       string
+		wantErr    string
+		wantLine   int
+		wantColumn int
+	}{
+		{
+			name: "variables",
+			s:    `${ Metadata.Name }.${ Metadata.Namespace }.${ Cluster.BaseDomain }`,
+			want: "app.team-a.example.com",
+		},
+		{
+			name: "maps",
+			s:    `${ Metadata.Labels.tier }-${ ConfigMaps.settings.port }`,
+			want: "web-8080",
+		},
+		{
+			name: "numbers and booleans",
+			s:    `${ Values.replicas * 2 } ${ 1.5 } ${ Values.replicas > 2 }`,
+			want: "6 1.5 true",
+		},
+		{
+			name: "conditional",
+			s:    `${ Metadata.Namespace.startsWith("team-") ? "shared" : "dedicated" }`,
+			want: "shared",
+		},
+		{
+			name: "string extensions",
+			s:    `${ Metadata.Name.upperAscii() }`,
+			want: "APP",
+		},
+		{
+			name: "dns functions",
+			s:    `${ dnsLabel("My App") } ${ truncateLabel(63, "abc") }`,
+			want: "my-app abc",
+		},
+		{
+			name: "lists are JSON",
+			s:    `${ Values.hosts.map(h, h + "." + Cluster.BaseDomain) }`,
+			want: `["a.example.com","b.example.com"]`,
+		},
+		{
+			name: "JSON fields are their value",
+			s:    `${ Spec.Parameters.tls }`,
+			want: "true",
+		},
+		{
+			name: "null is empty",
+			s:    `[${ null }]`,
+			want: "[]",
+		},
+		{
+			name:       "compile error",
+			s:          `${ Metadata.Name + }`,
+			wantErr:    `CEL expression "Metadata.Name +": Syntax error`,
+			wantLine:   1,
+			wantColumn: 19,
+		},
+		{
+			name:       "undeclared variable",
+			s:          "host: ${ Metadata.Name }\npath: ${ Meta.Name }",
+			wantErr:    `CEL expression "Meta.Name": undeclared reference to 'Meta'`,
+			wantLine:   2,
+			wantColumn: 10,
+		},
+		{
+			name:       "missing key",
+			s:          `${ Metadata.Name }.${ Metadata.Labels.team }`,
+			wantErr:    `CEL expression "Metadata.Labels.team": no such key: team`,
+			wantLine:   1,
+			wantColumn: 23,
+		},
+		{
+			name:    "function error",
+			s:       `${ truncateLabel(-1, Metadata.Name) }`,
+			wantErr: `CEL expression "truncateLabel(-1, Metadata.Name)": truncateLabel: length -1 is less than 1`,
+		},
+		{
+			name:    "type error",
+			s:       `${ Metadata.Name + 1 }`,
+			wantErr: `CEL expression "Metadata.Name + 1": no such overload`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := CELEngine{}.NewEvaluator(context.Background(), opt)
+			got, err := e.Evaluate(tt.s)
+			if tt.wantErr != "" {
+				var celErr *CELError
+				if !errors.As(err, &celErr) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("celEvaluator.Evaluate() error = %v, wantErr %q", err, tt.wantErr)
+				}
+				if tt.wantLine > 0 && (celErr.Line != tt.wantLine || celErr.Column != tt.wantColumn) {
+					t.Errorf("celEvaluator.Evaluate() error at %d:%d, want %d:%d", celErr.Line, celErr.Column, tt.wantLine, tt.wantColumn)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("celEvaluator.Evaluate() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("celEvaluator.Evaluate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRender_cel(t *testing.T) {
+	ing := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				"example.com/owner": "${ Metadata.Namespace }",
+				"example.com/path":  "{{ .Metadata.Name }}",
+			},
+		},
+		Spec: networkingv1.IngressSpec{
+			Rules: []networkingv1.IngressRule{
+				{Host: `${ Metadata.Name + "." + Cluster.BaseDomain }`},
+			},
+		},
+	}
+	c := NewCache(10)
+	got, err := Render(context.Background(), ing, Options{
+		Metadata: metav1.ObjectMeta{Name: "app", Namespace: "team-a", Generation: 1},
+		Cluster:  Cluster{BaseDomain: "example.com"},
+		Engine:   CELEngine{},
+		Cache:    c,
+		CacheKey: "team-a/app",
+	})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if host := got.Spec.Rules[0].Host; host != "app.example.com" {
+		t.Errorf("Render() host = %v, want app.example.com", host)
+	}
+	if owner := got.Annotations["example.com/owner"]; owner != "team-a" {
+		t.Errorf("Render() owner = %v, want team-a", owner)
+	}
+	if path := got.Annotations["example.com/path"]; path != "{{ .Metadata.Name }}" {
+		t.Errorf("Render() left Go template actions = %v, want them unchanged", path)
+	}
+	if _, ok := c.items["team-a/app"].Value.(*cacheEntry).value.(*celPrograms); !ok {
+		t.Errorf("Render() did not cache the compiled expressions")
+	}
+}
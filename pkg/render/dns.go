@@ -0,0 +1,74 @@
+package render
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/idna"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// hashSuffixLength is the number of hex characters of the hash appended to truncated labels.
+const hashSuffixLength = 8
+
+// sanitizeLabel converts s into an RFC 1123 label character set: lower case
+// alphanumerics and '-', starting and ending with an alphanumeric.
+func sanitizeLabel(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, c := range strings.ToLower(s) {
+		if ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') {
+			b.WriteRune(c)
+			dash = false
+			continue
+		}
+		if !dash {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.Trim(b.String(), "-")
+}
+
+// truncateLabel shortens s to at most max characters. When s is too long, the
+// end is replaced by a hash of the whole of s so that different long inputs
+// stay distinct and the same input always gives the same result.
+func truncateLabel(max int, s string) (string, error) {
+	if max < 1 {
+		return "", fmt.Errorf("truncateLabel: length %d is less than 1", max)
+	}
+	return shortenLabel(max, s), nil
+}
+
+// shortenLabel is truncateLabel for a max of at least 1.
+func shortenLabel(max int, s string) string {
+	if len(s) <= max {
+		return s
+	}
+	if max <= hashSuffixLength+1 {
+		return hashOf(s)[:max]
+	}
+	sum := hashOf(s)[:hashSuffixLength]
+	prefix := strings.TrimRight(s[:max-hashSuffixLength-1], "-.")
+	if prefix == "" {
+		return sum
+	}
+	return prefix + "-" + sum
+}
+
+// dnsLabel turns s into a valid RFC 1123 label of at most 63 characters.
+func dnsLabel(s string) string {
+	return shortenLabel(validation.DNS1123LabelMaxLength, sanitizeLabel(s))
+}
+
+// punycode converts an internationalized domain name into its ASCII form.
+func punycode(s string) (string, error) {
+	return idna.Lookup.ToASCII(s)
+}
+
+func hashOf(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,145 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_sanitizeLabel(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{
+			name: "valid",
+			s:    "hoge-1",
+			want: "hoge-1",
+		},
+		{
+			name: "upper case and invalid characters",
+			s:    "Feature/ABC_123",
+			want: "feature-abc-123",
+		},
+		{
+			name: "leading and trailing invalid characters",
+			s:    "--.hoge.--",
+			want: "hoge",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeLabel(tt.s); got != tt.want {
+				t.Errorf("sanitizeLabel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_truncateLabel(t *testing.T) {
+	long := strings.Repeat("a", 70)
+	tests := []struct {
+		name    string
+		max     int
+		s       string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "short",
+			max:  63,
+			s:    "hoge",
+			want: "hoge",
+		},
+		{
+			name: "long",
+			max:  63,
+			s:    long,
+			want: strings.Repeat("a", 54) + "-" + hashOf(long)[:8],
+		},
+		{
+			name: "max shorter than the hash",
+			max:  4,
+			s:    long,
+			want: hashOf(long)[:4],
+		},
+		{
+			name: "prefix of separators only",
+			max:  12,
+			s:    "---" + long,
+			want: hashOf("---" + long)[:8],
+		},
+		{
+			name:    "max less than 1",
+			max:     -1,
+			s:       long,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := truncateLabel(tt.max, tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("truncateLabel() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got != tt.want {
+				t.Errorf("truncateLabel() = %v, want %v", got, tt.want)
+			}
+			if len(got) > tt.max {
+				t.Errorf("truncateLabel() length = %d, want <= %d", len(got), tt.max)
+			}
+		})
+	}
+}
+
+func Test_dnsLabel(t *testing.T) {
+	ns := "preview-" + strings.Repeat("Feature_Branch-", 5)
+	got := dnsLabel("www-" + ns)
+	if len(got) != 63 {
+		t.Errorf("dnsLabel() length = %d, want 63", len(got))
+	}
+	if got != dnsLabel("www-"+ns) {
+		t.Errorf("dnsLabel() is not stable")
+	}
+	if got == dnsLabel("www-"+ns+"x") {
+		t.Errorf("dnsLabel() does not distinguish long inputs")
+	}
+	if strings.ToLower(got) != got || strings.ContainsAny(got, "_") {
+		t.Errorf("dnsLabel() = %v, is not a DNS label", got)
+	}
+}
+
+func Test_punycode(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "ascii",
+			s:    "example.com",
+			want: "example.com",
+		},
+		{
+			name: "idn",
+			s:    "bücher.example.com",
+			want: "xn--bcher-kva.example.com",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := punycode(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("punycode() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("punycode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package render
+
+import (
+	"sort"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+)
+
+// excludedFuncs are sprig functions whose result depends on the clock, randomness,
+// the operator's environment or the network. They are removed so that rendering
+// the same IngressTemplate always produces the same Ingress.
+var excludedFuncs = []string{
+	// clock
+	"ago",
+	"durationRound",
+	"toDate",
+	"mustToDate",
+	"dateModify",
+	"date_modify",
+	"mustDateModify",
+	"must_date_modify",
+
+	// randomness
+	"shuffle",
+	"randInt",
+	"bcrypt",
+	"htpasswd",
+	"encryptAES",
+	"decryptAES",
+	"genPrivateKey",
+	"buildCustomCert",
+	"genCA",
+	"genCAWithKey",
+	"genSelfSignedCert",
+	"genSelfSignedCertWithKey",
+	"genSignedCert",
+	"genSignedCertWithKey",
+
+	// operating system
+	"osBase",
+	"osClean",
+	"osDir",
+	"osExt",
+	"osIsAbs",
+}
+
+// defaultFuncs are the functions available to templates. They are shared by all
+// renderers and must not be modified.
+var defaultFuncs = funcMap()
+
+// The Sprig functions that boundTemplate limits.
+var (
+	sprigUntil     = sprig.HermeticTxtFuncMap()["until"].(func(int) []int)
+	sprigUntilStep = sprig.HermeticTxtFuncMap()["untilStep"].(func(int, int, int) []int)
+	sprigSeq       = sprig.HermeticTxtFuncMap()["seq"].(func(...int) string)
+	sprigRepeat    = sprig.HermeticTxtFuncMap()["repeat"].(func(int, string) string)
+)
+
+// funcMap returns the functions available to templates.
+func funcMap() template.FuncMap {
+	f := sprig.HermeticTxtFuncMap()
+	for _, name := range excludedFuncs {
+		delete(f, name)
+	}
+	f["sanitizeLabel"] = sanitizeLabel
+	f["truncateLabel"] = truncateLabel
+	f["dnsLabel"] = dnsLabel
+	f["punycode"] = punycode
+	// lookup and include are bound to each render, see compiledSet.bind
+	f["lookup"] = lookupFunc(nil)
+	f["include"] = (&boundTemplate{tpl: template.New("")}).include
+	return f
+}
+
+// FuncNames returns the sorted names of the functions available to templates.
+func FuncNames() []string {
+	f := funcMap()
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
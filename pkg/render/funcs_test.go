@@ -0,0 +1,92 @@
+package render
+
+import (
+	"testing"
+)
+
+func Test_funcMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		tmpl    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "lower",
+			tmpl: `{{ .key1 | lower }}`,
+			want: "value",
+		},
+		{
+			name: "trim",
+			tmpl: `{{ .key2 | trim }}`,
+			want: "spaced",
+		},
+		{
+			name: "replace",
+			tmpl: `{{ .key1 | replace "Value" "hoge" }}`,
+			want: "hoge",
+		},
+		{
+			name: "trunc",
+			tmpl: `{{ .key1 | trunc 3 }}`,
+			want: "Val",
+		},
+		{
+			name: "sha256sum",
+			tmpl: `{{ .key1 | sha256sum | trunc 8 }}`,
+			want: "8e37953d",
+		},
+		{
+			name: "default",
+			tmpl: `{{ .missing | default "fallback" }}`,
+			want: "fallback",
+		},
+		{
+			name:    "now is not available",
+			tmpl:    `{{ now }}`,
+			wantErr: true,
+		},
+		{
+			name:    "env is not available",
+			tmpl:    `{{ env "HOME" }}`,
+			wantErr: true,
+		},
+		{
+			name:    "randAlpha is not available",
+			tmpl:    `{{ randAlpha 3 }}`,
+			wantErr: true,
+		},
+		{
+			name:    "uuidv4 is not available",
+			tmpl:    `{{ uuidv4 }}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTemplateEvaluator(map[string]interface{}{
+				"key1": "Value",
+				"key2": "  spaced ",
+			})
+			got, err := r.Evaluate(tt.tmpl)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("templateEvaluator.Evaluate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("templateEvaluator.Evaluate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFuncNames(t *testing.T) {
+	names := FuncNames()
+	for _, excluded := range append([]string{"now", "env", "expandenv", "getHostByName"}, excludedFuncs...) {
+		for _, name := range names {
+			if name == excluded {
+				t.Errorf("FuncNames() contains %s", excluded)
+			}
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package render
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// Library is a named set of {{ define }} blocks shared by IngressTemplates.
+type Library struct {
+	Name      string
+	Templates string
+
+	// Delimiters are the action delimiters used by Templates, which can differ
+	// from those of the IngressTemplates using the library.
+	Delimiters Delimiters
+}
+
+// parseLibraries returns the template set holding the named templates of the libraries.
+func parseLibraries(funcs template.FuncMap, delims Delimiters, strict bool, libraries []Library) (*template.Template, error) {
+	tpl := template.New("").Delims(delims.Left, delims.Right).Funcs(funcs)
+	if strict {
+		tpl.Option("missingkey=error")
+	}
+
+	definedIn := map[string]string{}
+	for _, lib := range libraries {
+		parsed, err := template.New(lib.Name).Delims(lib.Delimiters.Left, lib.Delimiters.Right).Funcs(funcs).Parse(lib.Templates)
+		if err != nil {
+			return nil, fmt.Errorf("library %q: %w", lib.Name, err)
+		}
+		for _, t := range parsed.Templates() {
+			if t.Name() == lib.Name || t.Tree == nil {
+				continue
+			}
+			if other, ok := definedIn[t.Name()]; ok {
+				return nil, fmt.Errorf("template %q is defined in both library %q and library %q", t.Name(), other, lib.Name)
+			}
+			definedIn[t.Name()] = lib.Name
+			if _, err := tpl.AddParseTree(t.Name(), t.Tree); err != nil {
+				return nil, fmt.Errorf("library %q: %w", lib.Name, err)
+			}
+		}
+	}
+
+	trees := treesOf(tpl)
+	if err := checkTemplateRefs(tpl, trees); err != nil {
+		return nil, err
+	}
+	for _, tree := range trees {
+		limitTree(tree.Root)
+	}
+	return tpl, nil
+}
+
+// checkTemplateRefs makes sure that every {{ template }} action of trees refers
+// to a template defined in trees or in base.
+func checkTemplateRefs(base *template.Template, trees map[string]*parse.Tree) error {
+	defined := func(name string) bool {
+		_, ok := trees[name]
+		return ok || base.Lookup(name) != nil
+	}
+
+	names := make([]string, 0, len(trees))
+	for name := range trees {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if missing := findMissing(defined, trees[name].Root); missing != "" {
+			return missingTemplate(base, missing)
+		}
+	}
+	return nil
+}
+
+// findMissing returns the name of the first template referred to below node that is not defined.
+func findMissing(defined func(string) bool, node parse.Node) string {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return ""
+		}
+		for _, c := range n.Nodes {
+			if missing := findMissing(defined, c); missing != "" {
+				return missing
+			}
+		}
+	case *parse.IfNode:
+		return findMissingInBranch(defined, &n.BranchNode)
+	case *parse.RangeNode:
+		return findMissingInBranch(defined, &n.BranchNode)
+	case *parse.WithNode:
+		return findMissingInBranch(defined, &n.BranchNode)
+	case *parse.TemplateNode:
+		if !defined(n.Name) {
+			return n.Name
+		}
+	}
+	return ""
+}
+
+func findMissingInBranch(defined func(string) bool, n *parse.BranchNode) string {
+	if missing := findMissing(defined, n.List); missing != "" {
+		return missing
+	}
+	return findMissing(defined, n.ElseList)
+}
+
+// treesOf returns the parse trees of the templates of tpl by name.
+func treesOf(tpl *template.Template) map[string]*parse.Tree {
+	trees := map[string]*parse.Tree{}
+	for _, t := range tpl.Templates() {
+		if t.Tree != nil {
+			trees[t.Name()] = t.Tree
+		}
+	}
+	return trees
+}
+
+func missingTemplate(tpl *template.Template, name string) error {
+	var defined []string
+	for _, t := range tpl.Templates() {
+		if t.Name() != "" && t.Tree != nil {
+			defined = append(defined, t.Name())
+		}
+	}
+	if len(defined) == 0 {
+		return fmt.Errorf("template %q is not defined, no library templates are available", name)
+	}
+	sort.Strings(defined)
+	return fmt.Errorf("template %q is not defined, available templates: %s", name, strings.Join(defined, ", "))
+}
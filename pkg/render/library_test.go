@@ -0,0 +1,110 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_renderer_render_libraries(t *testing.T) {
+	libraries := []Library{
+		{
+			Name:      "hosts",
+			Templates: `{{ define "host" }}{{ .name }}.example.com{{ end }}`,
+		},
+		{
+			Name:      "annotations",
+			Templates: `{{ define "issuer" }}letsencrypt-{{ .env }}{{ end }}{{ define "wrapped" }}[{{ template "host" . }}]{{ end }}`,
+		},
+	}
+
+	tests := []struct {
+		name      string
+		libraries []Library
+		tmpl      string
+		want      string
+		wantErr   string
+	}{
+		{
+			name:      "template",
+			libraries: libraries,
+			tmpl:      `{{ template "host" . }}`,
+			want:      "app.example.com",
+		},
+		{
+			name:      "include",
+			libraries: libraries,
+			tmpl:      `{{ include "issuer" . | upper }}`,
+			want:      "LETSENCRYPT-DEV",
+		},
+		{
+			name:      "template across libraries",
+			libraries: libraries,
+			tmpl:      `{{ template "wrapped" . }}`,
+			want:      "[app.example.com]",
+		},
+		{
+			name:      "missing template",
+			libraries: libraries,
+			tmpl:      `{{ if true }}{{ template "hots" . }}{{ end }}`,
+			wantErr:   `template "hots" is not defined, available templates: host, issuer, wrapped`,
+		},
+		{
+			name:      "missing include",
+			libraries: libraries,
+			tmpl:      `{{ include "hots" . }}`,
+			wantErr:   `template "hots" is not defined, available templates: host, issuer, wrapped`,
+		},
+		{
+			name:    "no libraries",
+			tmpl:    `{{ include "host" . }}`,
+			wantErr: `template "host" is not defined, no library templates are available`,
+		},
+		{
+			name: "defined twice",
+			libraries: []Library{
+				{Name: "a", Templates: `{{ define "host" }}a{{ end }}`},
+				{Name: "b", Templates: `{{ define "host" }}b{{ end }}`},
+			},
+			tmpl:    `{{ template "host" . }}`,
+			wantErr: `template "host" is defined in both library "a" and library "b"`,
+		},
+		{
+			name:      "define in a field",
+			libraries: libraries,
+			tmpl:      `{{ define "host" }}evil.example.com{{ end }}{{ template "host" . }}`,
+			wantErr:   `template "host" is defined in a field, named templates can only be defined in libraries`,
+		},
+		{
+			name:    "block in a field",
+			tmpl:    `{{ block "host" . }}{{ .name }}.example.com{{ end }}`,
+			wantErr: `template "host" is defined in a field`,
+		},
+		{
+			name: "library parse error",
+			libraries: []Library{
+				{Name: "broken", Templates: `{{ define "host" }}{{ .name }`},
+			},
+			tmpl:    `{{ template "host" . }}`,
+			wantErr: `library "broken"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTemplateEvaluator(map[string]interface{}{"name": "app", "env": "dev"})
+			r.libraries = tt.libraries
+			got, err := r.Evaluate(tt.tmpl)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("templateEvaluator.Evaluate() error = %v, wantErr %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("templateEvaluator.Evaluate() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("templateEvaluator.Evaluate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"text/template/parse"
+	"time"
+)
+
+// Limits bound the time and output of a render, so that a faulty template can
+// neither hold up the operator nor produce an oversized Ingress. Zero values
+// mean no limit.
+type Limits struct {
+	// Timeout is the wall time a render may take.
+	Timeout time.Duration
+
+	// MaxFieldSize is the size in bytes a single rendered field may have.
+	MaxFieldSize int
+
+	// MaxObjectSize is the size in bytes all rendered fields of an Ingress may have together.
+	MaxObjectSize int
+
+	// MaxDepth is how deep {{ template }} and include calls may be nested.
+	MaxDepth int
+}
+
+// TimeoutError reports a render that took longer than Limits.Timeout.
+type TimeoutError struct {
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("rendering took longer than %s", e.Timeout)
+}
+
+// OutputSizeError reports a rendered field, or all rendered fields of an
+// Ingress together when Object is set, larger than Limit bytes.
+type OutputSizeError struct {
+	Limit  int
+	Object bool
+}
+
+func (e *OutputSizeError) Error() string {
+	if e.Object {
+		return fmt.Sprintf("rendered Ingress is larger than %d bytes", e.Limit)
+	}
+	return fmt.Sprintf("rendered value is larger than %d bytes", e.Limit)
+}
+
+// DepthError reports a call of Template nested deeper than Limit.
+type DepthError struct {
+	Limit    int
+	Template string
+}
+
+func (e *DepthError) Error() string {
+	return fmt.Sprintf("template %q is nested deeper than %d levels", e.Template, e.Limit)
+}
+
+// SequenceLengthError reports a call of until, untilStep or seq that would
+// return more than Limit elements. Limit is Limits.MaxFieldSize.
+type SequenceLengthError struct {
+	Func  string
+	Limit int
+}
+
+func (e *SequenceLengthError) Error() string {
+	return fmt.Sprintf("%s would return more than %d elements", e.Func, e.Limit)
+}
+
+// limitError returns the limit error wrapped by err, or err itself when it is
+// not caused by a limit. text/template wraps the error of every nested call,
+// which would otherwise repeat once per level of a runaway recursion.
+func limitError(err error) error {
+	var (
+		timeoutErr *TimeoutError
+		sizeErr    *OutputSizeError
+		depthErr   *DepthError
+		seqErr     *SequenceLengthError
+	)
+	switch {
+	case errors.As(err, &timeoutErr):
+		return timeoutErr
+	case errors.As(err, &sizeErr):
+		return sizeErr
+	case errors.As(err, &depthErr):
+		return depthErr
+	case errors.As(err, &seqErr):
+		return seqErr
+	}
+	return err
+}
+
+// contextError returns the error for a render whose context is done.
+func contextError(ctx context.Context, limits Limits) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) && limits.Timeout > 0 {
+		return &TimeoutError{Timeout: limits.Timeout}
+	}
+	return ctx.Err()
+}
+
+// limitWriter collects the output of a template and stops it as soon as it
+// grows larger than limit or the render is cancelled.
+type limitWriter struct {
+	bytes.Buffer
+	ctx    context.Context
+	limits Limits
+	limit  int
+}
+
+func (w *limitWriter) Write(p []byte) (int, error) {
+	if w.ctx.Err() != nil {
+		return 0, contextError(w.ctx, w.limits)
+	}
+	if w.limit > 0 && w.Len()+len(p) > w.limit {
+		return 0, &OutputSizeError{Limit: w.limit}
+	}
+	return w.Buffer.Write(p)
+}
+
+// checkRenderFunc is the function limitTree calls in every iteration of a
+// range. It can not be called by templates, as it is only known once they
+// are parsed.
+const checkRenderFunc = "_checkRender"
+
+// limitTree replaces the {{ template }} actions below node with the equivalent
+// include calls, so that every nested template goes through include, which
+// enforces Limits.MaxDepth and Limits.MaxFieldSize. It also makes every
+// iteration of a range check that the render is still running, so that a loop
+// without output stops once the render times out.
+func limitTree(node parse.Node) parse.Node {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return n
+		}
+		for i, c := range n.Nodes {
+			n.Nodes[i] = limitTree(c)
+		}
+	case *parse.IfNode:
+		limitBranch(&n.BranchNode)
+	case *parse.RangeNode:
+		limitBranch(&n.BranchNode)
+		if n.List != nil {
+			n.List.Nodes = append([]parse.Node{callNode(n.Pos, n.Line, checkRenderFunc)}, n.List.Nodes...)
+		}
+	case *parse.WithNode:
+		limitBranch(&n.BranchNode)
+	case *parse.TemplateNode:
+		var data parse.Node = &parse.NilNode{NodeType: parse.NodeNil, Pos: n.Pos}
+		if n.Pipe != nil {
+			data = n.Pipe
+		}
+		return callNode(n.Pos, n.Line, "include",
+			&parse.StringNode{NodeType: parse.NodeString, Pos: n.Pos, Quoted: fmt.Sprintf("%q", n.Name), Text: n.Name},
+			data,
+		)
+	}
+	return node
+}
+
+func limitBranch(n *parse.BranchNode) {
+	limitTree(n.List)
+	limitTree(n.ElseList)
+}
+
+// callNode returns an action calling the function name with args.
+func callNode(pos parse.Pos, line int, name string, args ...parse.Node) *parse.ActionNode {
+	return &parse.ActionNode{
+		NodeType: parse.NodeAction,
+		Pos:      pos,
+		Line:     line,
+		Pipe: &parse.PipeNode{
+			NodeType: parse.NodePipe,
+			Pos:      pos,
+			Line:     line,
+			Cmds: []*parse.CommandNode{{
+				NodeType: parse.NodeCommand,
+				Pos:      pos,
+				Args:     append([]parse.Node{parse.NewIdentifier(name).SetPos(pos)}, args...),
+			}},
+		},
+	}
+}
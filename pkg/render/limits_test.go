@@ -0,0 +1,201 @@
+package render
+
+import (
+	"context"
+	"errors"
+	"math"
+	"runtime"
+	"testing"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+func TestRender_limits(t *testing.T) {
+	libraries := []Library{{
+		Name: "lib",
+		Templates: `{{ define "loop" }}{{ template "loop" . }}{{ end }}` +
+			`{{ define "includeLoop" }}{{ include "includeLoop" . }}{{ end }}` +
+			`{{ define "big" }}{{ range until 10 }}xxxxxxxxxx{{ end }}{{ end }}` +
+			`{{ define "nested" }}{{ if gt . 0 }}{{ template "nested" (sub . 1) }}{{ end }}{{ end }}`,
+	}}
+
+	tests := []struct {
+		name   string
+		host   string
+		limits Limits
+		want   error
+	}{
+		{
+			name:   "runaway output",
+			host:   `{{ range until 100000 }}{{ range until 100000 }}x{{ end }}{{ end }}`,
+			limits: Limits{Timeout: 10 * time.Millisecond},
+			want:   &TimeoutError{Timeout: 10 * time.Millisecond},
+		},
+		{
+			name:   "runaway loop without output",
+			host:   `{{ range until 3000 }}{{ range until 3000 }}{{ end }}{{ end }}`,
+			limits: Limits{Timeout: 10 * time.Millisecond},
+			want:   &TimeoutError{Timeout: 10 * time.Millisecond},
+		},
+		{
+			name:   "field size",
+			host:   `{{ range until 10 }}xxxxxxxxxx{{ end }}`,
+			limits: Limits{MaxFieldSize: 10},
+			want:   &OutputSizeError{Limit: 10},
+		},
+		{
+			name:   "field size in include",
+			host:   `{{ include "big" . | len }}`,
+			limits: Limits{MaxFieldSize: 10},
+			want:   &OutputSizeError{Limit: 10},
+		},
+		{
+			name:   "object size",
+			host:   `{{ range until 100 }}x{{ end }}`,
+			limits: Limits{MaxObjectSize: 100},
+			want:   &OutputSizeError{Limit: 100, Object: true},
+		},
+		{
+			name:   "sequence length",
+			host:   `{{ range until 1000000000 }}{{ end }}`,
+			limits: Limits{MaxFieldSize: 10},
+			want:   &SequenceLengthError{Func: "until", Limit: 10},
+		},
+		{
+			name:   "repeat",
+			host:   `{{ repeat 1000000000 "x" }}`,
+			limits: Limits{MaxFieldSize: 10},
+			want:   &OutputSizeError{Limit: 10},
+		},
+		{
+			name:   "template recursion",
+			host:   `{{ template "loop" . }}`,
+			limits: Limits{MaxDepth: 10},
+			want:   &DepthError{Limit: 10, Template: "loop"},
+		},
+		{
+			name:   "include recursion",
+			host:   `{{ include "includeLoop" . }}`,
+			limits: Limits{MaxDepth: 10},
+			want:   &DepthError{Limit: 10, Template: "includeLoop"},
+		},
+		{
+			name:   "nesting within the depth",
+			host:   `{{ template "nested" 10 }}`,
+			limits: Limits{MaxDepth: 11},
+		},
+		{
+			name:   "within the limits",
+			host:   `{{ range until 10 }}x{{ end }}`,
+			limits: Limits{Timeout: time.Second, MaxFieldSize: 10, MaxObjectSize: 100, MaxDepth: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ing := &networkingv1.Ingress{
+				Spec: networkingv1.IngressSpec{
+					Rules: []networkingv1.IngressRule{
+						{Host: `{{ "app" }}`},
+						{Host: tt.host},
+					},
+				},
+			}
+			_, err := Render(context.Background(), ing, Options{Libraries: libraries, Limits: tt.limits})
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Render() error = %v", err)
+				}
+				return
+			}
+
+			var fieldErr *FieldError
+			if !errors.As(err, &fieldErr) || fieldErr.Path != "spec.rules[1].host" {
+				t.Fatalf("Render() error = %v, want an error for spec.rules[1].host", err)
+			}
+			if fieldErr.Err.Error() != tt.want.Error() {
+				t.Errorf("Render() error = %v, want %v", fieldErr.Err, tt.want)
+			}
+			switch tt.want.(type) {
+			case *TimeoutError:
+				var e *TimeoutError
+				if !errors.As(err, &e) {
+					t.Errorf("Render() error is not a *TimeoutError")
+				}
+			case *OutputSizeError:
+				var e *OutputSizeError
+				if !errors.As(err, &e) {
+					t.Errorf("Render() error is not an *OutputSizeError")
+				}
+			case *DepthError:
+				var e *DepthError
+				if !errors.As(err, &e) {
+					t.Errorf("Render() error is not a *DepthError")
+				}
+			case *SequenceLengthError:
+				var e *SequenceLengthError
+				if !errors.As(err, &e) {
+					t.Errorf("Render() error is not a *SequenceLengthError")
+				}
+			}
+		})
+	}
+}
+
+func TestRender_timeoutStopsTemplates(t *testing.T) {
+	ing := &networkingv1.Ingress{
+		Spec: networkingv1.IngressSpec{
+			Rules: []networkingv1.IngressRule{
+				{Host: `{{ $l := until 100000 }}{{ range $l }}{{ range $l }}{{ end }}{{ end }}`},
+			},
+		},
+	}
+	before := runtime.NumGoroutine()
+	for i := 0; i < 5; i++ {
+		var timeoutErr *TimeoutError
+		if _, err := Render(context.Background(), ing, Options{Limits: Limits{Timeout: 50 * time.Millisecond}}); !errors.As(err, &timeoutErr) {
+			t.Fatalf("Render() error = %v, want a *TimeoutError", err)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := runtime.NumGoroutine(); got > before {
+		t.Errorf("%d goroutines are running after the renders timed out, want %d", got, before)
+	}
+}
+
+func TestSequenceLength(t *testing.T) {
+	tests := []struct {
+		start, stop, step int
+		want              uint64
+	}{
+		{start: 0, stop: 10, step: 1, want: 10},
+		{start: 0, stop: 10, step: 3, want: 4},
+		{start: 10, stop: 0, step: -1, want: 10},
+		{start: 0, stop: 10, step: -1, want: 0},
+		{start: 0, stop: 10, step: 0, want: 0},
+		{start: math.MinInt, stop: math.MaxInt, step: 1, want: math.MaxUint64},
+	}
+	for _, tt := range tests {
+		if got := sequenceLength(tt.start, tt.stop, tt.step); got != tt.want {
+			t.Errorf("sequenceLength(%d, %d, %d) = %d, want %d", tt.start, tt.stop, tt.step, got, tt.want)
+		}
+	}
+}
+
+func TestRender_canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ing := &networkingv1.Ingress{
+		Spec: networkingv1.IngressSpec{
+			Rules: []networkingv1.IngressRule{{Host: `{{ "app" }}.example.com`}},
+		},
+	}
+	if _, err := Render(ctx, ing, Options{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Render() error = %v, want %v", err, context.Canceled)
+	}
+}
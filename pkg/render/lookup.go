@@ -0,0 +1,28 @@
+package render
+
+import "fmt"
+
+// Lookuper reads objects for the lookup template function.
+type Lookuper interface {
+	// Lookup returns the object as unstructured content, or nil when it does not exist.
+	// An empty namespace stands for the namespace of the IngressTemplate.
+	Lookup(apiVersion, kind, namespace, name string) (map[string]interface{}, error)
+}
+
+// lookupFunc returns the lookup template function backed by l. A missing object
+// is returned as an empty map, so templates can test whether it exists with if.
+func lookupFunc(l Lookuper) func(apiVersion, kind, namespace, name string) (map[string]interface{}, error) {
+	return func(apiVersion, kind, namespace, name string) (map[string]interface{}, error) {
+		if l == nil {
+			return nil, fmt.Errorf("lookup is not available")
+		}
+		obj, err := l.Lookup(apiVersion, kind, namespace, name)
+		if err != nil {
+			return nil, err
+		}
+		if obj == nil {
+			return map[string]interface{}{}, nil
+		}
+		return obj, nil
+	}
+}
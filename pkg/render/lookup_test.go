@@ -0,0 +1,73 @@
+package render
+
+import (
+	"fmt"
+	"testing"
+)
+
+type fakeLookuper map[string]map[string]interface{}
+
+func (f fakeLookuper) Lookup(apiVersion, kind, namespace, name string) (map[string]interface{}, error) {
+	if kind == "Forbidden" {
+		return nil, fmt.Errorf("lookup of %s is not allowed", kind)
+	}
+	return f[apiVersion+"/"+kind+"/"+namespace+"/"+name], nil
+}
+
+func Test_lookupFunc(t *testing.T) {
+	objects := fakeLookuper{
+		"v1/Service//app": {
+			"spec": map[string]interface{}{
+				"ports": []interface{}{
+					map[string]interface{}{"name": "http", "port": int64(80)},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		lookuper Lookuper
+		tmpl     string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "found",
+			lookuper: objects,
+			tmpl:     `{{ (index (lookup "v1" "Service" "" "app").spec.ports 0).name }}`,
+			want:     "http",
+		},
+		{
+			name:     "not found is empty",
+			lookuper: objects,
+			tmpl:     `{{ if lookup "v1" "Secret" "" "tls" }}found{{ else }}missing{{ end }}`,
+			want:     "missing",
+		},
+		{
+			name:     "error",
+			lookuper: objects,
+			tmpl:     `{{ lookup "v1" "Forbidden" "" "app" }}`,
+			wantErr:  true,
+		},
+		{
+			name:    "no lookuper",
+			tmpl:    `{{ lookup "v1" "Service" "" "app" }}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTemplateEvaluator(map[string]interface{}{})
+			r.lookup = tt.lookuper
+			got, err := r.Evaluate(tt.tmpl)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("templateEvaluator.Evaluate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("templateEvaluator.Evaluate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,116 +1,688 @@
 package render
 
 import (
-	"bytes"
-	"text/template"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 
 	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// Options holds the values available to templates and how they are rendered.
 type Options struct {
+	// Metadata is the metadata of the IngressTemplate, available as .Metadata.
 	Metadata metav1.ObjectMeta
+
+	// Spec is the spec of the IngressTemplate, available as .Spec.
+	Spec interface{}
+
+	// Namespace is the Namespace of the IngressTemplate, available as .Namespace.
+	Namespace Namespace
+
+	// Cluster holds operator-wide values, available as .Cluster.
+	Cluster Cluster
+
+	// ConfigMaps holds the data of the referenced ConfigMaps by name, available as .ConfigMaps.
+	ConfigMaps map[string]map[string]string
+
+	// Secrets holds the decoded data of the referenced Secrets by name, available as .Secrets.
+	Secrets map[string]map[string]string
+
+	// Values holds the parameters of the IngressTemplate with their defaults applied, available as .Values.
+	Values map[string]interface{}
+
+	// Lookup reads the objects requested by the lookup template function.
+	// Without it, lookup fails.
+	Lookup Lookuper
+
+	// Libraries hold named templates that can be used with {{ template }} and include.
+	Libraries []Library
+
+	// Delimiters replace the default "{{" and "}}" action delimiters of the templates.
+	Delimiters Delimiters
+
+	// Cache, when set, keeps the compiled templates under CacheKey until the
+	// generation in Metadata, the libraries or the delimiters change.
+	Cache    *Cache
+	CacheKey string
+
+	// StrictMissingKeys makes a reference to a missing map key a render error
+	// instead of rendering "<no value>".
+	StrictMissingKeys bool
+
+	// Limits bound the time and output of the render.
+	Limits Limits
+
+	// Engine evaluates the expressions in the string fields. Defaults to TemplateEngine.
+	Engine Engine
+
+	// vars are values available to templates in addition to those of ToMap.
+	vars map[string]interface{}
+}
+
+// Engine evaluates the expressions in the string fields of an Ingress, such as
+// the actions of a Go template.
+type Engine interface {
+	// NewEvaluator returns the evaluator for a single render with opt. ctx is
+	// done when the render times out or is cancelled.
+	NewEvaluator(ctx context.Context, opt *Options) Evaluator
+}
+
+// Evaluator evaluates the expressions of a single render. It is not used concurrently.
+type Evaluator interface {
+	// HasExpression reports whether s contains an expression. Strings
+	// without expressions are used as they are.
+	HasExpression(s string) bool
+
+	// Evaluate returns s with its expressions replaced by their results.
+	Evaluate(s string) (string, error)
+
+	// Close releases the resources of the evaluator once the render is done.
+	Close()
+}
+
+// Delimiters are the action delimiters of templates. Empty values stand for
+// the default "{{" and "}}".
+type Delimiters struct {
+	Left  string
+	Right string
+}
+
+func (d Delimiters) left() string {
+	if d.Left == "" {
+		return "{{"
+	}
+	return d.Left
+}
+
+// Namespace is the part of a Namespace object available to templates.
+type Namespace struct {
+	Name        string
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+// Cluster holds the values configured on the operator.
+type Cluster struct {
+	// Name is the name of the cluster the operator runs in.
+	Name string
+
+	// BaseDomain is the DNS domain under which Ingress hosts of the cluster are created.
+	BaseDomain string
+
+	// Values are arbitrary key value pairs.
+	Values map[string]string
 }
 
 func (opt *Options) ToMap() map[string]interface{} {
-	return map[string]interface{}{
-		"Metadata": opt.Metadata,
+	m := map[string]interface{}{
+		"Metadata":   opt.Metadata,
+		"Spec":       opt.Spec,
+		"Namespace":  opt.Namespace,
+		"Cluster":    opt.Cluster,
+		"ConfigMaps": opt.ConfigMaps,
+		"Secrets":    opt.Secrets,
+		"Values":     opt.Values,
+	}
+	for k, v := range opt.vars {
+		m[k] = v
 	}
+	return m
 }
 
-func Render(ing *networkingv1.Ingress, opt Options) (*networkingv1.Ingress, error) {
-	r := newRenderer(opt.ToMap())
+// RenderString renders tmpl, the template of the field at path, on its own.
+func RenderString(ctx context.Context, path, tmpl string, opt Options) (string, error) {
+	r := opt.renderer(ctx)
+	defer r.close()
 
-	if ing.Labels != nil {
-		for k, v := range ing.Labels {
-			if ret, err := r.render(v); err == nil {
-				ing.Labels[k] = ret
-			} else {
-				return nil, err
-			}
+	out, err := r.render(tmpl)
+	if err != nil {
+		return "", fieldError(path, tmpl, err)
+	}
+	return out, nil
+}
+
+// hostVar is the name of the host RenderHosts renders a template for.
+const hostVar = "Host"
+
+// RenderHosts renders tmpl, the template of the field at path, once for each
+// of hosts, with the host available as .Host, or Host in CEL expressions.
+// The results are in the order of hosts.
+func RenderHosts(ctx context.Context, path, tmpl string, hosts []string, opt Options) ([]string, error) {
+	out := make([]string, len(hosts))
+	for i, host := range hosts {
+		opt.vars = map[string]interface{}{hostVar: host}
+		r := opt.renderer(ctx)
+		ret, err := r.render(tmpl)
+		r.close()
+		if err != nil {
+			return nil, fieldError(path, tmpl, fmt.Errorf("host %q: %w", host, err))
 		}
+		out[i] = ret
 	}
+	return out, nil
+}
 
-	if ing.Annotations != nil {
-		for k, v := range ing.Annotations {
-			if ret, err := r.render(v); err == nil {
-				ing.Annotations[k] = ret
-			} else {
-				return nil, err
-			}
+// renderer returns a renderer for the values and settings of opt.
+func (opt *Options) renderer(ctx context.Context) *renderer {
+	r := &renderer{ctx: ctx, limits: opt.Limits}
+	if opt.Limits.Timeout > 0 {
+		r.ctx, r.cancel = context.WithTimeout(ctx, opt.Limits.Timeout)
+	}
+	engine := opt.Engine
+	if engine == nil {
+		engine = TemplateEngine{}
+	}
+	r.eval = engine.NewEvaluator(r.ctx, opt)
+	return r
+}
+
+// Render renders every string field of the labels, annotations and spec of ing
+// and validates the rendered hosts, secret names and service names.
+// Exceeding opt.Limits fails the render with a *TimeoutError, *OutputSizeError
+// or *DepthError.
+func Render(ctx context.Context, ing *networkingv1.Ingress, opt Options) (*networkingv1.Ingress, error) {
+	r := opt.renderer(ctx)
+	defer r.close()
+
+	if err := r.renderMetadata(ing); err != nil {
+		return nil, err
+	}
+
+	if err := r.walk("spec", reflect.ValueOf(&ing.Spec).Elem()); err != nil {
+		return nil, err
+	}
+
+	if err := Validate(ing); err != nil {
+		return nil, err
+	}
+
+	return ing, nil
+}
+
+// RenderYAML renders the labels and annotations of ing like Render, and replaces
+// its spec with specTemplate rendered as a whole and decoded as an IngressSpec.
+// This allows actions such as range and if to produce any number of rules.
+func RenderYAML(ctx context.Context, ing *networkingv1.Ingress, specTemplate string, opt Options) (*networkingv1.Ingress, error) {
+	r := opt.renderer(ctx)
+	defer r.close()
+
+	if err := r.renderMetadata(ing); err != nil {
+		return nil, err
+	}
+
+	out, err := r.render(specTemplate)
+	if err != nil {
+		return nil, fieldError("spec", specTemplate, err)
+	}
+
+	spec, err := decodeSpec(out)
+	if err != nil {
+		return nil, &FieldError{Path: "spec", Err: err}
+	}
+	ing.Spec = *spec
+
+	if err := Validate(ing); err != nil {
+		return nil, err
+	}
+
+	return ing, nil
+}
+
+// SpecTemplate is a template of an IngressSpec, such as the IngressSpecTemplate
+// of an IngressTemplate. The elements of its lists that have a string field
+// tagged json:"when" are only included when that field renders to true.
+type SpecTemplate interface {
+	// IngressSpec returns the IngressSpec the rendered template describes,
+	// without the elements whose when field is "false".
+	IngressSpec() networkingv1.IngressSpec
+}
+
+// RenderSpec renders the labels and annotations of ing like Render, and replaces
+// its spec with the IngressSpec of specTemplate once its string fields are
+// rendered. specTemplate must be a pointer and is rendered in place, except for
+// the elements left out, whose when field is set to "false" and which are not
+// rendered any further. The paths of the errors are those of specTemplate.
+func RenderSpec(ctx context.Context, ing *networkingv1.Ingress, specTemplate SpecTemplate, opt Options) (*networkingv1.Ingress, error) {
+	r := opt.renderer(ctx)
+	defer r.close()
+
+	if err := r.renderMetadata(ing); err != nil {
+		return nil, err
+	}
+
+	if err := r.walk("spec", reflect.ValueOf(specTemplate).Elem()); err != nil {
+		return nil, err
+	}
+	ing.Spec = specTemplate.IngressSpec()
+
+	if err := Validate(ing); err != nil {
+		var fieldErr *FieldError
+		if errors.As(err, &fieldErr) {
+			fieldErr.Path = r.templatePath(fieldErr.Path)
 		}
+		return nil, err
+	}
+
+	return ing, nil
+}
+
+func (r *renderer) renderMetadata(ing *networkingv1.Ingress) error {
+	labels, err := r.renderKeys("metadata.labels", ing.Labels)
+	if err != nil {
+		return err
+	}
+	ing.Labels = labels
+	if err := r.walk("metadata.labels", reflect.ValueOf(&ing.Labels).Elem()); err != nil {
+		return err
 	}
 
-	if ing.Spec.TLS != nil {
-		for i, tls := range ing.Spec.TLS {
-			secretName, err := r.render(tls.SecretName)
+	annotations, err := r.renderKeys("metadata.annotations", ing.Annotations)
+	if err != nil {
+		return err
+	}
+	ing.Annotations = annotations
+	return r.walk("metadata.annotations", reflect.ValueOf(&ing.Annotations).Elem())
+}
+
+// renderKeys returns m with its keys rendered. Two keys that render to the same
+// key are an error, since one value would silently replace the other.
+func (r *renderer) renderKeys(path string, m map[string]string) (map[string]string, error) {
+	if m == nil {
+		return nil, nil
+	}
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out := make(map[string]string, len(m))
+	from := make(map[string]string, len(m))
+	for _, k := range keys {
+		rendered, err := r.render(k)
+		if err != nil {
+			return nil, fieldError(fmt.Sprintf("%s[%s]", path, k), k, err)
+		}
+		if other, ok := from[rendered]; ok {
+			return nil, &FieldError{Path: fmt.Sprintf("%s[%s]", path, k), Err: fmt.Errorf("key renders to %q, the same as key %q", rendered, other)}
+		}
+		from[rendered] = k
+		out[rendered] = m[k]
+	}
+	return out, nil
+}
+
+// FieldError reports a template that could not be rendered into the field at Path.
+type FieldError struct {
+	Path string
+	Err  error
+
+	// Line and Column are the 1-based position of the error in the template
+	// of the field, or 0 when the engine does not know it.
+	Line   int
+	Column int
+
+	// Snippet is the line of the template at Line.
+	Snippet string
+}
+
+func (e *FieldError) Error() string {
+	switch {
+	case e.Line > 0 && e.Column > 0:
+		return fmt.Sprintf("%s: %s (line %d, column %d: %q)", e.Path, e.Err, e.Line, e.Column, e.Snippet)
+	case e.Line > 0:
+		return fmt.Sprintf("%s: %s (line %d: %q)", e.Path, e.Err, e.Line, e.Snippet)
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// locatedError is implemented by the errors of engines that know where in the
+// template of a field they occurred.
+type locatedError interface {
+	error
+	location() (line, column int)
+}
+
+// fieldError returns the error of the field at path, whose template is source.
+func fieldError(path, source string, err error) *FieldError {
+	fieldErr := &FieldError{Path: path, Err: err}
+	var located locatedError
+	if errors.As(err, &located) {
+		fieldErr.Line, fieldErr.Column = located.location()
+		fieldErr.Snippet = snippet(source, fieldErr.Line, fieldErr.Column)
+	}
+	return fieldErr
+}
+
+// maxSnippet is the length of the longest snippet. Longer lines are cut
+// around the column of the error.
+const maxSnippet = 80
+
+// snippet returns the line of source at line, shortened to maxSnippet around column.
+func snippet(source string, line, column int) string {
+	lines := strings.Split(source, "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	s := lines[line-1]
+	if len(s) <= maxSnippet {
+		return strings.TrimSpace(s)
+	}
+
+	start := column - 1 - maxSnippet/2
+	if start < 0 {
+		start = 0
+	}
+	end := start + maxSnippet
+	if end > len(s) {
+		end = len(s)
+		start = end - maxSnippet
+	}
+	out := s[start:end]
+	if start > 0 {
+		out = "..." + out
+	}
+	if end < len(s) {
+		out += "..."
+	}
+	return out
+}
+
+// renderer renders the string fields of an Ingress with an Evaluator.
+type renderer struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	limits Limits
+	eval   Evaluator
+
+	// size is the size of the output of all fields so far.
+	size int
+
+	// dropped holds the paths of the list elements left out by their when field.
+	dropped map[string]bool
+
+	// path is the path of the field being walked.
+	path []pathElem
+}
+
+// close releases the resources of the renderer once the render is done.
+func (r *renderer) close() {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.eval.Close()
+}
+
+// walk renders all string values reachable from v in place. v must be settable.
+// Constant strings are left as they are, and walking them does not allocate.
+func (r *renderer) walk(root string, v reflect.Value) error {
+	r.path = append(r.path[:0], pathElem{name: root})
+	return r.walkValue(v)
+}
+
+// walkAt walks v, the child elem of the value being walked.
+func (r *renderer) walkAt(elem pathElem, v reflect.Value) error {
+	r.path = append(r.path, elem)
+	err := r.walkValue(v)
+	r.path = r.path[:len(r.path)-1]
+	return err
+}
+
+func (r *renderer) walkValue(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.String:
+		ret, err := r.render(v.String())
+		if err != nil {
+			return fieldError(r.pathString(), v.String(), err)
+		}
+		if ret != v.String() {
+			v.SetString(ret)
+		}
+
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return r.walkValue(v.Elem())
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		elem := reflect.New(v.Elem().Type()).Elem()
+		elem.Set(v.Elem())
+		if err := r.walkValue(elem); err != nil {
+			return err
+		}
+		v.Set(elem)
+
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			var err error
+			if name, inline := fieldName(f); inline {
+				err = r.walkValue(v.Field(i))
+			} else {
+				err = r.walkAt(pathElem{name: name}, v.Field(i))
+			}
 			if err != nil {
-				return nil, err
+				return err
 			}
-			ing.Spec.TLS[i].SecretName = secretName
+		}
 
-			for ii, host := range tls.Hosts {
-				if ret, err := r.render(host); err == nil {
-					ing.Spec.TLS[i].Hosts[ii] = ret
-				} else {
-					return nil, err
-				}
+	case reflect.Slice, reflect.Array:
+		if when, ok := whenField(v.Type().Elem()); ok && v.Kind() == reflect.Slice {
+			return r.walkWhen(v, when)
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := r.walkAt(pathElem{index: i}, v.Index(i)); err != nil {
+				return err
 			}
 		}
+
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		key := reflect.New(v.Type().Key()).Elem()
+		elem := reflect.New(v.Type().Elem()).Elem()
+		iter := v.MapRange()
+		for iter.Next() {
+			key.SetIterKey(iter)
+			elem.SetIterValue(iter)
+			if err := r.walkAt(pathElem{key: key}, elem); err != nil {
+				return err
+			}
+			v.SetMapIndex(key, elem)
+		}
+
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		// scalars other than strings can not carry a template
+
+	default:
+		if v.IsZero() {
+			return nil
+		}
+		return &FieldError{Path: r.pathString(), Err: fmt.Errorf("unsupported field kind %s", v.Kind())}
 	}
 
-	if ing.Spec.Rules != nil {
-		for i, rule := range ing.Spec.Rules {
-			if ret, err := r.render(rule.Host); err == nil {
-				ing.Spec.Rules[i].Host = ret
-			} else {
-				return nil, err
+	return nil
+}
+
+// walkWhen renders the elements of the slice v whose when field, the field at
+// index field, renders to true. The when field is rendered first, so that an
+// element left out can not fail the render.
+func (r *renderer) walkWhen(v reflect.Value, field int) error {
+	for i := 0; i < v.Len(); i++ {
+		r.path = append(r.path, pathElem{index: i})
+		elem := v.Index(i)
+		include, err := r.when(elem.Field(field))
+		switch {
+		case err != nil:
+		case !include:
+			if r.dropped == nil {
+				r.dropped = map[string]bool{}
 			}
+			r.dropped[r.pathString()] = true
+		default:
+			err = r.walkValue(elem)
+		}
+		r.path = r.path[:len(r.path)-1]
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-			if rule.HTTP != nil && len(rule.HTTP.Paths) > 0 {
-				for ii, path := range rule.HTTP.Paths {
-					if path.Backend.Resource != nil && path.Backend.Resource.Name != "" {
-						if ret, err := r.render(path.Backend.Resource.Name); err == nil {
-							ing.Spec.Rules[i].HTTP.Paths[ii].Backend.Resource.Name = ret
-						} else {
-							return nil, err
-						}
-					}
-					if path.Backend.Service != nil && path.Backend.Service.Name != "" {
-						if ret, err := r.render(path.Backend.Service.Name); err == nil {
-							ing.Spec.Rules[i].HTTP.Paths[ii].Backend.Service.Name = ret
-						} else {
-							return nil, err
-						}
-					}
+// when renders the when field v to "true" or "false" and reports whether its
+// element is included. An empty when field includes the element.
+func (r *renderer) when(v reflect.Value) (bool, error) {
+	if v.String() == "" {
+		return true, nil
+	}
+	ret, err := r.render(v.String())
+	if err != nil {
+		return false, fieldError(r.pathString()+".when", v.String(), err)
+	}
+	include, err := strconv.ParseBool(strings.TrimSpace(ret))
+	if err != nil {
+		return false, &FieldError{Path: r.pathString() + ".when", Err: fmt.Errorf("when renders to %q instead of true or false", ret)}
+	}
+	v.SetString(strconv.FormatBool(include))
+	return include, nil
+}
+
+// whenField returns the index of the string field tagged json:"when" of the struct type t.
+func whenField(t reflect.Type) (int, bool) {
+	if t.Kind() != reflect.Struct {
+		return 0, false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if name, _, _ := strings.Cut(f.Tag.Get("json"), ","); name == "when" && f.Type.Kind() == reflect.String {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// templatePath returns the path in the template of the rendered field at path,
+// whose list indices do not count the elements left out by their when field.
+func (r *renderer) templatePath(path string) string {
+	if len(r.dropped) == 0 {
+		return path
+	}
+	var out strings.Builder
+	for {
+		open := strings.IndexByte(path, '[')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(path[open:], ']')
+		if end < 0 {
+			break
+		}
+		end += open
+		out.WriteString(path[:open])
+		if n, err := strconv.Atoi(path[open+1 : end]); err == nil {
+			parent, i := out.String(), -1
+			for kept := -1; kept < n; {
+				i++
+				if !r.dropped[fmt.Sprintf("%s[%d]", parent, i)] {
+					kept++
 				}
 			}
+			fmt.Fprintf(&out, "[%d]", i)
+		} else {
+			out.WriteString(path[open : end+1])
 		}
+		path = path[end+1:]
 	}
+	out.WriteString(path)
+	return out.String()
+}
 
-	return ing, nil
+// pathElem is an element of the path of the field being walked: the name of
+// a struct field, or of the root, the key of a map entry when key is valid,
+// or else the index of a list element.
+type pathElem struct {
+	name  string
+	key   reflect.Value
+	index int
 }
 
-type renderer struct {
-	data map[string]interface{}
+// pathString returns the path of the field being walked, such as
+// spec.rules[0].host. It is only built when a field is reported.
+func (r *renderer) pathString() string {
+	var b strings.Builder
+	for i, e := range r.path {
+		switch {
+		case e.name != "":
+			if i > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(e.name)
+		case e.key.IsValid():
+			fmt.Fprintf(&b, "[%s]", e.key)
+		default:
+			fmt.Fprintf(&b, "[%d]", e.index)
+		}
+	}
+	return b.String()
 }
 
-func newRenderer(data map[string]interface{}) *renderer {
-	return &renderer{
-		data: data,
+// fieldName returns the JSON name of the struct field f, and whether f is
+// inlined into its parent instead.
+func fieldName(f reflect.StructField) (string, bool) {
+	name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if strings.Contains(opts, "inline") || (name == "" && f.Anonymous) {
+		return "", true
+	}
+	if name == "" || name == "-" {
+		name = f.Name
 	}
+	return name, false
 }
 
+// render renders tmpl and counts the output against the limits.
 func (r *renderer) render(tmpl string) (string, error) {
-	tpl, err := template.New("").Parse(tmpl)
-	if err != nil {
-		return "", err
+	out := tmpl
+	// a string without expressions renders to itself, so it is not parsed
+	if r.eval.HasExpression(tmpl) {
+		if r.ctx.Err() != nil {
+			return "", contextError(r.ctx, r.limits)
+		}
+		var err error
+		if out, err = r.eval.Evaluate(tmpl); err != nil {
+			return "", err
+		}
 	}
 
-	var buf bytes.Buffer
-	if err := tpl.Execute(&buf, r.data); err != nil {
-		return "", err
+	r.size += len(out)
+	if r.limits.MaxFieldSize > 0 && len(out) > r.limits.MaxFieldSize {
+		return "", &OutputSizeError{Limit: r.limits.MaxFieldSize}
 	}
-
-	return buf.String(), nil
+	if r.limits.MaxObjectSize > 0 && r.size > r.limits.MaxObjectSize {
+		return "", &OutputSizeError{Limit: r.limits.MaxObjectSize, Object: true}
+	}
+	return out, nil
 }
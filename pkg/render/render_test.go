@@ -1,17 +1,28 @@
 package render
 
 import (
+	"context"
+	"errors"
 	"reflect"
+	"strings"
 	"testing"
 
 	v1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ingresstemplatev1alpha1 "github.com/takumakume/ingress-template-operator/api/v1alpha1"
 )
 
-func Test_renderer_render(t *testing.T) {
+// newRenderer returns a renderer evaluating Go templates with data.
+func newRenderer(data map[string]interface{}) *renderer {
+	return &renderer{ctx: context.Background(), eval: newTemplateEvaluator(data)}
+}
+
+func Test_templateEvaluator_Evaluate(t *testing.T) {
 	type fields struct {
-		data map[string]interface{}
+		data   map[string]interface{}
+		strict bool
 	}
 	type args struct {
 		tmpl string
@@ -50,19 +61,43 @@ func Test_renderer_render(t *testing.T) {
 			},
 			want: "hoge",
 		},
+		{
+			name: "missing key",
+			fields: fields{
+				data: map[string]interface{}{
+					"key1": "value1",
+				},
+			},
+			args: args{
+				tmpl: "{{ .key2 }}",
+			},
+			want: "<no value>",
+		},
+		{
+			name: "missing key with strict",
+			fields: fields{
+				data: map[string]interface{}{
+					"key1": "value1",
+				},
+				strict: true,
+			},
+			args: args{
+				tmpl: "{{ .key2 }}",
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			r := &renderer{
-				data: tt.fields.data,
-			}
-			got, err := r.render(tt.args.tmpl)
+			r := newTemplateEvaluator(tt.fields.data)
+			r.strict = tt.fields.strict
+			got, err := r.Evaluate(tt.args.tmpl)
 			if (err != nil) != tt.wantErr {
-				t.Errorf("renderer.render() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("templateEvaluator.Evaluate() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 			if got != tt.want {
-				t.Errorf("renderer.render() = %v, want %v", got, tt.want)
+				t.Errorf("templateEvaluator.Evaluate() = %v, want %v", got, tt.want)
 			}
 		})
 	}
@@ -185,10 +220,145 @@ func TestRender(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "every string field",
+			args: args{
+				ing: &networkingv1.Ingress{
+					Spec: networkingv1.IngressSpec{
+						IngressClassName: stringPtr("{{ .Metadata.Namespace }}-class"),
+						DefaultBackend: &networkingv1.IngressBackend{
+							Service: &networkingv1.IngressServiceBackend{
+								Name: "{{ .Metadata.Namespace }}-default",
+								Port: networkingv1.ServiceBackendPort{
+									Name: "{{ .Metadata.Name }}",
+								},
+							},
+						},
+						Rules: []networkingv1.IngressRule{
+							{
+								IngressRuleValue: networkingv1.IngressRuleValue{
+									HTTP: &networkingv1.HTTPIngressRuleValue{
+										Paths: []networkingv1.HTTPIngressPath{
+											{
+												Path: "/{{ .Metadata.Name }}",
+												Backend: networkingv1.IngressBackend{
+													Resource: &v1.TypedLocalObjectReference{
+														APIGroup: stringPtr("{{ .Metadata.Name }}.example.com"),
+														Kind:     "{{ .Metadata.Name }}",
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+				opt: Options{
+					Metadata: metav1.ObjectMeta{
+						Name:      "fuga",
+						Namespace: "hoge",
+					},
+				},
+			},
+			want: &networkingv1.Ingress{
+				Spec: networkingv1.IngressSpec{
+					IngressClassName: stringPtr("hoge-class"),
+					DefaultBackend: &networkingv1.IngressBackend{
+						Service: &networkingv1.IngressServiceBackend{
+							Name: "hoge-default",
+							Port: networkingv1.ServiceBackendPort{
+								Name: "fuga",
+							},
+						},
+					},
+					Rules: []networkingv1.IngressRule{
+						{
+							IngressRuleValue: networkingv1.IngressRuleValue{
+								HTTP: &networkingv1.HTTPIngressRuleValue{
+									Paths: []networkingv1.HTTPIngressPath{
+										{
+											Path: "/fuga",
+											Backend: networkingv1.IngressBackend{
+												Resource: &v1.TypedLocalObjectReference{
+													APIGroup: stringPtr("fuga.example.com"),
+													Kind:     "fuga",
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "delimiters",
+			args: args{
+				ing: &networkingv1.Ingress{
+					ObjectMeta: metav1.ObjectMeta{
+						Annotations: map[string]string{
+							"helm": "{{ .Values.kept }}",
+						},
+					},
+					Spec: networkingv1.IngressSpec{
+						Rules: []networkingv1.IngressRule{
+							{
+								Host: `[[ include "host" . ]]`,
+							},
+						},
+					},
+				},
+				opt: Options{
+					Metadata: metav1.ObjectMeta{
+						Namespace: "hoge",
+					},
+					Delimiters: Delimiters{Left: "[[", Right: "]]"},
+					Libraries: []Library{
+						{
+							Name:      "default delimiters",
+							Templates: `{{ define "host" }}{{ .Metadata.Namespace }}.example.com{{ end }}`,
+						},
+					},
+				},
+			},
+			want: &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"helm": "{{ .Values.kept }}",
+					},
+				},
+				Spec: networkingv1.IngressSpec{
+					Rules: []networkingv1.IngressRule{
+						{
+							Host: "hoge.example.com",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "invalid template",
+			args: args{
+				ing: &networkingv1.Ingress{
+					Spec: networkingv1.IngressSpec{
+						Rules: []networkingv1.IngressRule{
+							{
+								Host: "{{ .Metadata.Namespace",
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := Render(tt.args.ing, tt.args.opt)
+			got, err := Render(context.Background(), tt.args.ing, tt.args.opt)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Render() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -202,7 +372,13 @@ func TestRender(t *testing.T) {
 
 func TestOptions_ToMap(t *testing.T) {
 	type fields struct {
-		Metadata metav1.ObjectMeta
+		Metadata   metav1.ObjectMeta
+		Spec       interface{}
+		Namespace  Namespace
+		Cluster    Cluster
+		ConfigMaps map[string]map[string]string
+		Secrets    map[string]map[string]string
+		Values     map[string]interface{}
 	}
 	tests := []struct {
 		name   string
@@ -220,13 +396,78 @@ func TestOptions_ToMap(t *testing.T) {
 				"Metadata": metav1.ObjectMeta{
 					Namespace: "hoge",
 				},
+				"Spec":       nil,
+				"Namespace":  Namespace{},
+				"Cluster":    Cluster{},
+				"ConfigMaps": map[string]map[string]string(nil),
+				"Secrets":    map[string]map[string]string(nil),
+				"Values":     map[string]interface{}(nil),
+			},
+		},
+		{
+			name: "all",
+			fields: fields{
+				Metadata: metav1.ObjectMeta{
+					Namespace: "hoge",
+				},
+				Spec: map[string]string{
+					"key": "value",
+				},
+				Namespace: Namespace{
+					Name:   "hoge",
+					Labels: map[string]string{"env": "dev"},
+				},
+				Cluster: Cluster{
+					Name:       "cluster1",
+					BaseDomain: "example.com",
+				},
+				ConfigMaps: map[string]map[string]string{
+					"cm": {"domain": "example.com"},
+				},
+				Secrets: map[string]map[string]string{
+					"secret": {"token": "xxx"},
+				},
+				Values: map[string]interface{}{
+					"service": "app",
+				},
+			},
+			want: map[string]interface{}{
+				"Metadata": metav1.ObjectMeta{
+					Namespace: "hoge",
+				},
+				"Spec": map[string]string{
+					"key": "value",
+				},
+				"Namespace": Namespace{
+					Name:   "hoge",
+					Labels: map[string]string{"env": "dev"},
+				},
+				"Cluster": Cluster{
+					Name:       "cluster1",
+					BaseDomain: "example.com",
+				},
+				"ConfigMaps": map[string]map[string]string{
+					"cm": {"domain": "example.com"},
+				},
+				"Secrets": map[string]map[string]string{
+					"secret": {"token": "xxx"},
+				},
+				"Values": map[string]interface{}{
+					"service": "app",
+				},
 			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			opt := &Options{
-				Metadata: tt.fields.Metadata,
+				Metadata:   tt.fields.Metadata,
+				Spec:       tt.fields.Spec,
+				Namespace:  tt.fields.Namespace,
+				Cluster:    tt.fields.Cluster,
+				ConfigMaps: tt.fields.ConfigMaps,
+				Secrets:    tt.fields.Secrets,
+				Values:     tt.fields.Values,
 			}
 			if got := opt.ToMap(); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("Options.ToMap() = %v, want %v", got, tt.want)
@@ -234,3 +475,487 @@ func TestOptions_ToMap(t *testing.T) {
 		})
 	}
 }
+
+func Test_renderer_walk(t *testing.T) {
+	type obj struct {
+		Name  string            `json:"name"`
+		Any   interface{}       `json:"any"`
+		Items map[string]string `json:"items"`
+		Func  func()            `json:"func"`
+	}
+	tests := []struct {
+		name     string
+		obj      obj
+		want     obj
+		wantPath string
+		wantErr  bool
+	}{
+		{
+			name: "strings",
+			obj: obj{
+				Name:  "{{ .key1 }}",
+				Any:   "{{ .key1 }}",
+				Items: map[string]string{"a": "{{ .key1 }}"},
+			},
+			want: obj{
+				Name:  "value1",
+				Any:   "value1",
+				Items: map[string]string{"a": "value1"},
+			},
+		},
+		{
+			name: "unsupported kind",
+			obj: obj{
+				Func: func() {},
+			},
+			wantPath: "obj.func",
+			wantErr:  true,
+		},
+		{
+			name: "error in map",
+			obj: obj{
+				Items: map[string]string{"a": "{{ .key1"},
+			},
+			wantPath: "obj.items[a]",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRenderer(map[string]interface{}{"key1": "value1"})
+			got := tt.obj
+			err := r.walk("obj", reflect.ValueOf(&got).Elem())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("renderer.walk() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				var fe *FieldError
+				if !errors.As(err, &fe) || fe.Path != tt.wantPath {
+					t.Errorf("renderer.walk() error = %v, want path %v", err, tt.wantPath)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("renderer.walk() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_renderer_walk_constantAllocs(t *testing.T) {
+	ing := benchmarkIngress()
+	ing.Spec.TLS = nil
+	ing.Spec.Rules[0].Host = "a.example.com"
+	ing.Spec.Rules[0].HTTP.Paths[0].Backend.Service.Name = "svc"
+	r := (&Options{}).renderer(context.Background())
+	spec := reflect.ValueOf(&ing.Spec).Elem()
+
+	if n := testing.AllocsPerRun(100, func() {
+		if _, err := r.render("constant"); err != nil {
+			t.Fatal(err)
+		}
+	}); n != 0 {
+		t.Errorf("renderer.render() of a constant allocates %v times, want 0", n)
+	}
+	if n := testing.AllocsPerRun(100, func() {
+		if err := r.walk("spec", spec); err != nil {
+			t.Fatal(err)
+		}
+	}); n != 0 {
+		t.Errorf("renderer.walk() of a constant spec allocates %v times, want 0", n)
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func Test_renderer_renderKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		m        map[string]string
+		want     map[string]string
+		wantPath string
+	}{
+		{
+			name: "nil",
+		},
+		{
+			name: "keys and values",
+			m: map[string]string{
+				"{{ .team }}.example.com/owner": "{{ .team }}",
+				"static":                        "value",
+			},
+			want: map[string]string{
+				"platform.example.com/owner": "{{ .team }}",
+				"static":                     "value",
+			},
+		},
+		{
+			name: "collision",
+			m: map[string]string{
+				"{{ .team }}":   "a",
+				"platform":      "b",
+				"not-colliding": "c",
+			},
+			wantPath: "metadata.labels[{{ .team }}]",
+		},
+		{
+			name: "invalid template",
+			m: map[string]string{
+				"{{ .team": "a",
+			},
+			wantPath: "metadata.labels[{{ .team]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRenderer(map[string]interface{}{"team": "platform"})
+			got, err := r.renderKeys("metadata.labels", tt.m)
+			if (err != nil) != (tt.wantPath != "") {
+				t.Fatalf("renderer.renderKeys() error = %v, wantPath %v", err, tt.wantPath)
+			}
+			if err != nil {
+				var fe *FieldError
+				if !errors.As(err, &fe) || fe.Path != tt.wantPath {
+					t.Errorf("renderer.renderKeys() error = %v, wantPath %v", err, tt.wantPath)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("renderer.renderKeys() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRender_errorLocation(t *testing.T) {
+	libraries := []Library{{
+		Name:      "lib",
+		Templates: `{{ define "host" }}{{ .Values.nope }}{{ end }}`,
+	}}
+
+	tests := []struct {
+		name         string
+		host         string
+		wantLine     int
+		wantColumn   int
+		wantSnippet  string
+		wantMessage  string
+		wantTemplate string
+	}{
+		{
+			name:        "missing key",
+			host:        `{{ .Values.hots }}.example.com`,
+			wantLine:    1,
+			wantColumn:  10,
+			wantSnippet: `{{ .Values.hots }}.example.com`,
+			wantMessage: `map has no entry for key "hots"`,
+		},
+		{
+			name:        "parse error on the second line",
+			host:        "{{- /* host */ -}}\n{{ if }}",
+			wantLine:    2,
+			wantSnippet: `{{ if }}`,
+			wantMessage: "missing value for if",
+		},
+		{
+			name:         "error in a library template",
+			host:         `{{ template "host" . }}`,
+			wantLine:     1,
+			wantColumn:   12,
+			wantSnippet:  `{{ template "host" . }}`,
+			wantMessage:  `map has no entry for key "nope"`,
+			wantTemplate: "host",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ing := &networkingv1.Ingress{
+				Spec: networkingv1.IngressSpec{
+					Rules: []networkingv1.IngressRule{{Host: "app"}, {Host: tt.host}},
+				},
+			}
+			_, err := Render(context.Background(), ing, Options{
+				Values:            map[string]interface{}{"host": "app"},
+				Libraries:         libraries,
+				StrictMissingKeys: true,
+			})
+
+			var fieldErr *FieldError
+			if !errors.As(err, &fieldErr) {
+				t.Fatalf("Render() error = %v, want a *FieldError", err)
+			}
+			if fieldErr.Path != "spec.rules[1].host" {
+				t.Errorf("Render() error path = %v, want spec.rules[1].host", fieldErr.Path)
+			}
+			if fieldErr.Line != tt.wantLine || fieldErr.Column != tt.wantColumn {
+				t.Errorf("Render() error at %d:%d, want %d:%d", fieldErr.Line, fieldErr.Column, tt.wantLine, tt.wantColumn)
+			}
+			if fieldErr.Snippet != tt.wantSnippet {
+				t.Errorf("Render() error snippet = %q, want %q", fieldErr.Snippet, tt.wantSnippet)
+			}
+			var templateErr *TemplateError
+			if !errors.As(err, &templateErr) {
+				t.Fatalf("Render() error = %v, want a *TemplateError", err)
+			}
+			if templateErr.Message != tt.wantMessage || templateErr.Template != tt.wantTemplate {
+				t.Errorf("Render() error = %q in %q, want %q in %q", templateErr.Message, templateErr.Template, tt.wantMessage, tt.wantTemplate)
+			}
+		})
+	}
+}
+
+func Test_snippet(t *testing.T) {
+	long := strings.Repeat("a", 100) + "{{ .Values.nope }}" + strings.Repeat("b", 100)
+	tests := []struct {
+		name   string
+		source string
+		line   int
+		column int
+		want   string
+	}{
+		{
+			name:   "short line",
+			source: "first\n  {{ .Values.nope }}  \nthird",
+			line:   2,
+			column: 6,
+			want:   "{{ .Values.nope }}",
+		},
+		{
+			name:   "line out of range",
+			source: "first",
+			line:   2,
+			want:   "",
+		},
+		{
+			name:   "long line is cut around the column",
+			source: long,
+			line:   1,
+			column: 101,
+			want:   "..." + long[60:140] + "...",
+		},
+		{
+			name:   "long line is cut at its start",
+			source: long,
+			line:   1,
+			column: 1,
+			want:   long[:80] + "...",
+		},
+		{
+			name:   "long line is cut at its end",
+			source: long,
+			line:   1,
+			column: len(long),
+			want:   "..." + long[len(long)-80:],
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := snippet(tt.source, tt.line, tt.column); got != tt.want {
+				t.Errorf("snippet() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderSpec(t *testing.T) {
+	backend := func(name string) networkingv1.IngressBackend {
+		return networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{Name: name}}
+	}
+	spec := func() *ingresstemplatev1alpha1.IngressSpecTemplate {
+		return &ingresstemplatev1alpha1.IngressSpecTemplate{
+			TLS: []ingresstemplatev1alpha1.IngressTLSTemplate{
+				{When: `{{ .Values.tls }}`, IngressTLS: networkingv1.IngressTLS{Hosts: []string{"app.example.com"}}},
+			},
+			Rules: []ingresstemplatev1alpha1.IngressRuleTemplate{
+				{
+					When: `{{ ne .Namespace.Labels.env "production" }}`,
+					Host: "{{ .Values.previewHost }}",
+				},
+				{
+					Host: "app.example.com",
+					HTTP: &ingresstemplatev1alpha1.HTTPIngressRuleValueTemplate{
+						Paths: []ingresstemplatev1alpha1.HTTPIngressPathTemplate{
+							{When: `{{ ne .Namespace.Labels.env "production" }}`, HTTPIngressPath: networkingv1.HTTPIngressPath{Path: "/admin", Backend: backend("admin")}},
+							{When: "true", HTTPIngressPath: networkingv1.HTTPIngressPath{Path: "/", Backend: backend("{{ .Values.service }}")}},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		env      string
+		tls      string
+		service  string
+		want     networkingv1.IngressSpec
+		wantPath string
+	}{
+		{
+			name:    "production",
+			env:     "production",
+			tls:     "true",
+			service: "app",
+			want: networkingv1.IngressSpec{
+				TLS: []networkingv1.IngressTLS{{Hosts: []string{"app.example.com"}}},
+				Rules: []networkingv1.IngressRule{{
+					Host: "app.example.com",
+					IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+						Paths: []networkingv1.HTTPIngressPath{{Path: "/", Backend: backend("app")}},
+					}},
+				}},
+			},
+		},
+		{
+			name:     "left out elements fail at their template path",
+			env:      "production",
+			tls:      "true",
+			service:  "app_1",
+			wantPath: "spec.rules[1].http.paths[1].backend.service.name",
+		},
+		{
+			name:     "rendered elements fail at their template path",
+			env:      "staging",
+			tls:      "false",
+			wantPath: "spec.rules[0].host",
+		},
+		{
+			name:     "when that is not a boolean",
+			env:      "production",
+			tls:      "enabled",
+			wantPath: "spec.tls[0].when",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opt := Options{
+				Namespace:         Namespace{Labels: map[string]string{"env": tt.env}},
+				Values:            map[string]interface{}{"tls": tt.tls, "service": tt.service},
+				StrictMissingKeys: true,
+			}
+			got, err := RenderSpec(context.Background(), &networkingv1.Ingress{}, spec(), opt)
+			if tt.wantPath != "" {
+				var fieldErr *FieldError
+				if !errors.As(err, &fieldErr) || fieldErr.Path != tt.wantPath {
+					t.Fatalf("RenderSpec() error = %v, want an error for %s", err, tt.wantPath)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RenderSpec() error = %v", err)
+			}
+			if !reflect.DeepEqual(got.Spec, tt.want) {
+				t.Errorf("RenderSpec() = %+v, want %+v", got.Spec, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderSpec_allPathsLeftOut(t *testing.T) {
+	spec := &ingresstemplatev1alpha1.IngressSpecTemplate{
+		Rules: []ingresstemplatev1alpha1.IngressRuleTemplate{{
+			Host: "admin.example.com",
+			HTTP: &ingresstemplatev1alpha1.HTTPIngressRuleValueTemplate{
+				Paths: []ingresstemplatev1alpha1.HTTPIngressPathTemplate{{
+					When: `{{ ne .Namespace.Labels.env "production" }}`,
+					HTTPIngressPath: networkingv1.HTTPIngressPath{
+						Path:     "/admin",
+						PathType: func() *networkingv1.PathType { p := networkingv1.PathTypePrefix; return &p }(),
+						Backend:  networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{Name: "admin", Port: networkingv1.ServiceBackendPort{Number: 80}}},
+					},
+				}},
+			},
+		}},
+	}
+	opt := Options{Namespace: Namespace{Labels: map[string]string{"env": "production"}}}
+	got, err := RenderSpec(context.Background(), &networkingv1.Ingress{}, spec, opt)
+	if err != nil {
+		t.Fatalf("RenderSpec() error = %v", err)
+	}
+	want := []networkingv1.IngressRule{{Host: "admin.example.com"}}
+	if !reflect.DeepEqual(got.Spec.Rules, want) {
+		t.Errorf("RenderSpec() rules = %+v, want %+v", got.Spec.Rules, want)
+	}
+}
+
+func Test_renderer_templatePath(t *testing.T) {
+	r := &renderer{dropped: map[string]bool{
+		"spec.rules[0]":               true,
+		"spec.rules[2]":               true,
+		"spec.rules[3].http.paths[0]": true,
+	}}
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "spec.rules[0].host", want: "spec.rules[1].host"},
+		{path: "spec.rules[1].http.paths[0].backend", want: "spec.rules[3].http.paths[1].backend"},
+		{path: "spec.tls[0].hosts[1]", want: "spec.tls[0].hosts[1]"},
+		{path: "metadata.labels[example.com/team]", want: "metadata.labels[example.com/team]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := r.templatePath(tt.path); got != tt.want {
+				t.Errorf("renderer.templatePath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderHosts(t *testing.T) {
+	hosts := []string{"example.com", "api.example.org"}
+	tests := []struct {
+		name    string
+		tmpl    string
+		engine  Engine
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "template",
+			tmpl: `{{ .Host | replace "." "-" }}-{{ .Metadata.Namespace }}`,
+			want: []string{"example-com-team-a", "api-example-org-team-a"},
+		},
+		{
+			name:   "cel",
+			tmpl:   `${ Host.replace(".", "-") }-tls`,
+			engine: CELEngine{},
+			want:   []string{"example-com-tls", "api-example-org-tls"},
+		},
+		{
+			name: "constant",
+			tmpl: "shared-tls",
+			want: []string{"shared-tls", "shared-tls"},
+		},
+		{
+			name:    "error",
+			tmpl:    "{{ .Host.Name }}",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderHosts(context.Background(), "spec.autoTLS.secretName", tt.tmpl, hosts, Options{
+				Metadata: metav1.ObjectMeta{Namespace: "team-a"},
+				Engine:   tt.engine,
+			})
+			if tt.wantErr {
+				var fieldErr *FieldError
+				if !errors.As(err, &fieldErr) || fieldErr.Path != "spec.autoTLS.secretName" {
+					t.Fatalf("RenderHosts() error = %v, want an error for spec.autoTLS.secretName", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RenderHosts() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RenderHosts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,486 @@
+package render
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+)
+
+// TemplateEngine evaluates the string fields as Go templates, with the Sprig
+// functions, lookup and the named templates of the libraries. It is the
+// default engine.
+type TemplateEngine struct{}
+
+func (TemplateEngine) NewEvaluator(ctx context.Context, opt *Options) Evaluator {
+	e := newTemplateEvaluator(opt.ToMap())
+	e.ctx = ctx
+	e.limits = opt.Limits
+	e.strict = opt.StrictMissingKeys
+	e.lookup = opt.Lookup
+	e.libraries = opt.Libraries
+	e.delims = opt.Delimiters
+	e.cache = opt.Cache
+	e.cacheKey = opt.CacheKey
+	e.generation = opt.Metadata.Generation
+	return e
+}
+
+type templateEvaluator struct {
+	ctx       context.Context
+	limits    Limits
+	data      map[string]interface{}
+	funcs     template.FuncMap
+	strict    bool
+	libraries []Library
+	delims    Delimiters
+	lookup    Lookuper
+
+	// cache shares the compiled templates stored under cacheKey between renders.
+	cache      *Cache
+	cacheKey   string
+	generation int64
+
+	set   *compiledSet
+	bound *boundTemplate
+
+	// out collects the output of the field being rendered.
+	out *limitWriter
+}
+
+func newTemplateEvaluator(data map[string]interface{}) *templateEvaluator {
+	return &templateEvaluator{
+		ctx:   context.Background(),
+		data:  data,
+		funcs: defaultFuncs,
+	}
+}
+
+func (e *templateEvaluator) HasExpression(s string) bool {
+	return strings.Contains(s, e.delims.left())
+}
+
+func (e *templateEvaluator) Close() {
+	if e.bound != nil {
+		e.set.release(e.bound)
+		e.bound = nil
+	}
+}
+
+func (e *templateEvaluator) Evaluate(tmpl string) (string, error) {
+	set, err := e.compiledSet()
+	if err != nil {
+		return "", err
+	}
+	f := set.compile(tmpl)
+	if f.err != nil {
+		return "", templateError(f.err)
+	}
+
+	b, err := e.template()
+	if err != nil {
+		return "", err
+	}
+	for name, tree := range f.trees {
+		if _, err := b.tpl.AddParseTree(name, tree); err != nil {
+			return "", err
+		}
+	}
+
+	if e.out == nil {
+		e.out = &limitWriter{ctx: e.ctx, limits: e.limits, limit: e.limits.MaxFieldSize}
+	}
+	e.out.Reset()
+
+	if e.limits.Timeout <= 0 {
+		if err := b.tpl.Execute(e.out, e.data); err != nil {
+			return "", templateError(limitError(err))
+		}
+		return e.out.String(), nil
+	}
+
+	// Templates are executed on their own goroutine, so that a template that
+	// loops without producing output can not hold up the render past its
+	// timeout. Such a template keeps running until it finishes, but the
+	// render no longer uses it or its output.
+	done := make(chan error, 1)
+	out := e.out
+	go func() {
+		done <- b.tpl.Execute(out, e.data)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return "", templateError(limitError(err))
+		}
+		return out.String(), nil
+	case <-e.ctx.Done():
+		b.close()
+		e.bound = nil
+		e.out = nil
+		return "", contextError(e.ctx, e.limits)
+	}
+}
+
+// TemplateError reports a Go template that could not be parsed or executed.
+type TemplateError struct {
+	// Line and Column are the 1-based position of the error in the template
+	// of the field. Column is 0 for parse errors.
+	Line   int
+	Column int
+
+	// Template is the named template the error occurred in, when the
+	// template of the field called it with {{ template }} or include.
+	Template string
+
+	// Message is the cause of the error, without the position.
+	Message string
+
+	Err error
+}
+
+func (e *TemplateError) Error() string {
+	if e.Template != "" {
+		return fmt.Sprintf("%s in template %q", e.Message, e.Template)
+	}
+	return e.Message
+}
+
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+func (e *TemplateError) location() (int, int) {
+	return e.Line, e.Column
+}
+
+// templateErrorPattern matches the errors of text/template, such as
+// `template: :1:12: executing "" at <.Values.hots>: map has no entry for key "hots"`.
+var templateErrorPattern = regexp.MustCompile(`(?s)^template: ([^:]*):(\d+)(?::(\d+))?: (?:executing "([^"]*)" at <.*?>: )?(.*)$`)
+
+// templateError returns err as a *TemplateError when it is an error of
+// text/template that reports its position. An error in a named template is
+// reported at the call in the template of the field, with the innermost message.
+func templateError(err error) error {
+	m := templateErrorPattern.FindStringSubmatch(err.Error())
+	if m == nil {
+		return err
+	}
+
+	templateErr := &TemplateError{Err: err, Message: m[5]}
+	templateErr.Line, _ = strconv.Atoi(m[2])
+	templateErr.Column, _ = strconv.Atoi(m[3])
+	for {
+		rest := strings.TrimPrefix(templateErr.Message, "error calling include: ")
+		if rest == templateErr.Message {
+			break
+		}
+		inner := templateErrorPattern.FindStringSubmatch(rest)
+		if inner == nil {
+			templateErr.Message = rest
+			break
+		}
+		templateErr.Template = inner[4]
+		if templateErr.Template == "" {
+			templateErr.Template = inner[1]
+		}
+		templateErr.Message = inner[5]
+	}
+	return templateErr
+}
+
+// compiledSet returns the compiled libraries and template strings for this
+// render, from the cache when there is one.
+func (e *templateEvaluator) compiledSet() (*compiledSet, error) {
+	if e.set == nil {
+		newSet := func() interface{} {
+			return newCompiledSet(e.funcs, e.delims, e.strict, e.libraries)
+		}
+		if e.cache != nil && e.cacheKey != "" {
+			e.set = e.cache.get(e.cacheKey, version(e.generation, e.delims, e.strict, e.libraries), newSet).(*compiledSet)
+		} else {
+			e.set = newSet().(*compiledSet)
+		}
+	}
+	return e.set, e.set.baseErr
+}
+
+// template returns the compiled libraries bound to this render.
+func (e *templateEvaluator) template() (*boundTemplate, error) {
+	if e.bound != nil {
+		return e.bound, nil
+	}
+	b, err := e.set.bind(e.ctx, e.lookup, e.limits)
+	if err != nil {
+		return nil, err
+	}
+	e.bound = b
+	return b, nil
+}
+
+// compiledSet holds the parsed libraries of an IngressTemplate and the parse
+// trees of its template strings.
+type compiledSet struct {
+	funcs  template.FuncMap
+	delims Delimiters
+
+	base    *template.Template
+	baseErr error
+
+	mu     sync.Mutex
+	fields map[string]*compiledField
+
+	// idle is a clone of base that is not used by a render at the moment.
+	// Renders of the same IngressTemplate rarely overlap, so one is enough.
+	idle *boundTemplate
+}
+
+type compiledField struct {
+	trees map[string]*parse.Tree
+	err   error
+}
+
+func newCompiledSet(funcs template.FuncMap, delims Delimiters, strict bool, libraries []Library) *compiledSet {
+	set := &compiledSet{
+		funcs:  funcs,
+		delims: delims,
+		fields: map[string]*compiledField{},
+	}
+	set.base, set.baseErr = parseLibraries(funcs, delims, strict, libraries)
+	return set
+}
+
+// compile returns the parse trees of tmpl, parsing it on first use. Template
+// references are checked against the libraries once, when tmpl is parsed.
+func (s *compiledSet) compile(tmpl string) *compiledField {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if f, ok := s.fields[tmpl]; ok {
+		return f
+	}
+
+	f := &compiledField{}
+	parsed, err := template.New("").Delims(s.delims.Left, s.delims.Right).Funcs(s.funcs).Parse(tmpl)
+	if err != nil {
+		f.err = err
+	} else if name := definedName(parsed); name != "" {
+		// The trees of a field are added to the template bound to the render,
+		// which later fields and renders reuse.
+		f.err = fmt.Errorf("template %q is defined in a field, named templates can only be defined in libraries", name)
+	} else {
+		f.trees = treesOf(parsed)
+		f.err = checkTemplateRefs(s.base, f.trees)
+		for _, tree := range f.trees {
+			limitTree(tree.Root)
+		}
+	}
+	s.fields[tmpl] = f
+	return f
+}
+
+// definedName returns the first name defined with {{ define }} or {{ block }}
+// in tpl, or "" when there is none.
+func definedName(tpl *template.Template) string {
+	var names []string
+	for _, t := range tpl.Templates() {
+		if t.Name() != tpl.Name() {
+			names = append(names, t.Name())
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Strings(names)
+	return names[0]
+}
+
+// bind returns a clone of the libraries whose include and lookup functions are
+// bound to a single render. It is returned to the set with release.
+func (s *compiledSet) bind(ctx context.Context, lookup Lookuper, limits Limits) (*boundTemplate, error) {
+	s.mu.Lock()
+	b := s.idle
+	s.idle = nil
+	s.mu.Unlock()
+
+	if b == nil {
+		tpl, err := s.base.Clone()
+		if err != nil {
+			return nil, err
+		}
+		b = &boundTemplate{tpl: tpl}
+		tpl.Funcs(template.FuncMap{
+			"include":       b.include,
+			"lookup":        b.lookupObject,
+			checkRenderFunc: b.checkRender,
+			"until":         b.until,
+			"untilStep":     b.untilStep,
+			"seq":           b.seq,
+			"repeat":        b.repeat,
+		})
+	}
+	b.ctx = ctx
+	b.lookup = lookup
+	b.limits = limits
+	return b, nil
+}
+
+func (s *compiledSet) release(b *boundTemplate) {
+	b.ctx = nil
+	b.lookup = nil
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.idle = b
+}
+
+// boundTemplate is a clone of the libraries of a compiled set used by one render at a time.
+type boundTemplate struct {
+	tpl *template.Template
+
+	ctx    context.Context
+	limits Limits
+	depth  int
+
+	// mu keeps lookup from being used after the render gave up on the template.
+	mu     sync.Mutex
+	lookup Lookuper
+	closed bool
+}
+
+// include renders the named template like {{ template }} but returns the
+// result as a string so it can be piped to other functions.
+func (b *boundTemplate) include(name string, data interface{}) (string, error) {
+	if b.tpl.Lookup(name) == nil {
+		return "", missingTemplate(b.tpl, name)
+	}
+	if b.limits.MaxDepth > 0 && b.depth >= b.limits.MaxDepth {
+		return "", &DepthError{Limit: b.limits.MaxDepth, Template: name}
+	}
+
+	b.depth++
+	defer func() { b.depth-- }()
+
+	w := &limitWriter{ctx: b.ctx, limits: b.limits, limit: b.limits.MaxFieldSize}
+	if err := b.tpl.ExecuteTemplate(w, name, data); err != nil {
+		return "", limitError(err)
+	}
+	return w.String(), nil
+}
+
+func (b *boundTemplate) lookupObject(apiVersion, kind, namespace, name string) (map[string]interface{}, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, contextError(b.ctx, b.limits)
+	}
+	return lookupFunc(b.lookup)(apiVersion, kind, namespace, name)
+}
+
+// checkRender fails once the render is cancelled or timed out.
+func (b *boundTemplate) checkRender() (string, error) {
+	if b.ctx.Err() != nil {
+		return "", contextError(b.ctx, b.limits)
+	}
+	return "", nil
+}
+
+// until, untilStep, seq and repeat are those of Sprig, but fail instead of
+// allocating more elements or bytes than a field may have.
+
+func (b *boundTemplate) until(count int) ([]int, error) {
+	step := 1
+	if count < 0 {
+		step = -1
+	}
+	if err := b.checkLength("until", sequenceLength(0, count, step)); err != nil {
+		return nil, err
+	}
+	return sprigUntil(count), nil
+}
+
+func (b *boundTemplate) untilStep(start, stop, step int) ([]int, error) {
+	if err := b.checkLength("untilStep", sequenceLength(start, stop, step)); err != nil {
+		return nil, err
+	}
+	return sprigUntilStep(start, stop, step), nil
+}
+
+func (b *boundTemplate) seq(params ...int) (string, error) {
+	start, step, end := 1, 1, 0
+	switch len(params) {
+	case 1:
+		end = params[0]
+	case 2:
+		start, end = params[0], params[1]
+	case 3:
+		start, step, end = params[0], params[1], params[2]
+	}
+	if step < 0 {
+		step = -step
+	}
+	if end < start {
+		start, end = end, start
+	}
+	if err := b.checkLength("seq", sequenceLength(start, end+1, step)); err != nil {
+		return "", err
+	}
+	return sprigSeq(params...), nil
+}
+
+func (b *boundTemplate) repeat(count int, str string) (string, error) {
+	if b.ctx.Err() != nil {
+		return "", contextError(b.ctx, b.limits)
+	}
+	if limit := b.limits.MaxFieldSize; limit > 0 && len(str) > 0 && count > limit/len(str) {
+		return "", &OutputSizeError{Limit: limit}
+	}
+	return sprigRepeat(count, str), nil
+}
+
+func (b *boundTemplate) checkLength(name string, length uint64) error {
+	if b.ctx.Err() != nil {
+		return contextError(b.ctx, b.limits)
+	}
+	if limit := b.limits.MaxFieldSize; limit > 0 && length > uint64(limit) {
+		return &SequenceLengthError{Func: name, Limit: limit}
+	}
+	return nil
+}
+
+// sequenceLength returns the number of elements from start up to, but not
+// including, stop in steps of step.
+func sequenceLength(start, stop, step int) uint64 {
+	switch {
+	case step > 0 && stop > start:
+		return (uint64(stop)-uint64(start)-1)/uint64(step) + 1
+	case step < 0 && stop < start:
+		return (uint64(start)-uint64(stop)-1)/uint64(-step) + 1
+	}
+	return 0
+}
+
+// close stops lookup for a template that is still executing after its render gave up on it.
+func (b *boundTemplate) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+}
+
+// version identifies everything, besides the template strings themselves,
+// that the compiled templates of an IngressTemplate depend on.
+func version(generation int64, delims Delimiters, strict bool, libraries []Library) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\x00%q\x00%q\x00%t\x00", generation, delims.Left, delims.Right, strict)
+	for _, lib := range libraries {
+		fmt.Fprintf(h, "%q\x00%q\x00%q\x00%q\x00", lib.Name, lib.Delimiters.Left, lib.Delimiters.Right, lib.Templates)
+	}
+	return strconv.FormatInt(generation, 10) + "-" + hex.EncodeToString(h.Sum(nil))
+}
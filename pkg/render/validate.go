@@ -0,0 +1,119 @@
+package render
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Validate checks that the rendered label and annotation keys, hosts, secret names
+// and service names of ing would be accepted by the API server.
+func Validate(ing *networkingv1.Ingress) error {
+	if err := validateKeys("metadata.labels", "label key", ing.Labels); err != nil {
+		return err
+	}
+	if err := validateKeys("metadata.annotations", "annotation key", ing.Annotations); err != nil {
+		return err
+	}
+
+	if ing.Spec.DefaultBackend != nil {
+		if err := validateBackend("spec.defaultBackend", ing.Spec.DefaultBackend); err != nil {
+			return err
+		}
+	}
+
+	for i, tls := range ing.Spec.TLS {
+		for ii, host := range tls.Hosts {
+			if err := ValidateHost(fmt.Sprintf("spec.tls[%d].hosts[%d]", i, ii), host); err != nil {
+				return err
+			}
+		}
+		if tls.SecretName != "" {
+			if err := ValidateSecretName(fmt.Sprintf("spec.tls[%d].secretName", i), tls.SecretName); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i, rule := range ing.Spec.Rules {
+		if rule.Host != "" {
+			if err := ValidateHost(fmt.Sprintf("spec.rules[%d].host", i), rule.Host); err != nil {
+				return err
+			}
+		}
+		if rule.HTTP == nil {
+			continue
+		}
+		for ii := range rule.HTTP.Paths {
+			path := fmt.Sprintf("spec.rules[%d].http.paths[%d].backend", i, ii)
+			if err := validateBackend(path, &rule.HTTP.Paths[ii].Backend); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateKeys(path, what string, m map[string]string) error {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if errs := validation.IsQualifiedName(k); len(errs) > 0 {
+			return invalid(fmt.Sprintf("%s[%s]", path, k), what, k, errs)
+		}
+	}
+	return nil
+}
+
+// ValidateHost checks that host, rendered into the field at path, is a valid
+// precise or wildcard host of an Ingress rule.
+func ValidateHost(path, host string) error {
+	var errs []string
+	if strings.HasPrefix(host, "*.") {
+		errs = validation.IsWildcardDNS1123Subdomain(host)
+	} else {
+		errs = validation.IsDNS1123Subdomain(host)
+	}
+	if len(errs) == 0 {
+		for _, label := range strings.Split(strings.TrimPrefix(host, "*."), ".") {
+			if labelErrs := validation.IsDNS1123Label(label); len(labelErrs) > 0 {
+				errs = append(errs, fmt.Sprintf("label %q: %s", label, strings.Join(labelErrs, ", ")))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return invalid(path, "host", host, errs)
+	}
+	return nil
+}
+
+// ValidateSecretName checks that name, rendered into the field at path, is a
+// valid name of a Secret.
+func ValidateSecretName(path, name string) error {
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return invalid(path, "secret name", name, errs)
+	}
+	return nil
+}
+
+func validateBackend(path string, backend *networkingv1.IngressBackend) error {
+	if backend.Service == nil {
+		return nil
+	}
+	if errs := validation.IsDNS1035Label(backend.Service.Name); len(errs) > 0 {
+		return invalid(path+".service.name", "service name", backend.Service.Name, errs)
+	}
+	return nil
+}
+
+func invalid(path, what, value string, errs []string) error {
+	return &FieldError{Path: path, Err: fmt.Errorf("invalid %s %q: %s", what, value, strings.Join(errs, "; "))}
+}
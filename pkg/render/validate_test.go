@@ -0,0 +1,124 @@
+package render
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		meta     metav1.ObjectMeta
+		spec     networkingv1.IngressSpec
+		wantPath string
+	}{
+		{
+			name: "valid",
+			spec: networkingv1.IngressSpec{
+				TLS: []networkingv1.IngressTLS{
+					{
+						Hosts:      []string{"hoge.example.com", "*.example.com"},
+						SecretName: "hoge-tls",
+					},
+				},
+				Rules: []networkingv1.IngressRule{
+					{
+						Host: "*.example.com",
+						IngressRuleValue: networkingv1.IngressRuleValue{
+							HTTP: &networkingv1.HTTPIngressRuleValue{
+								Paths: []networkingv1.HTTPIngressPath{
+									{
+										Backend: networkingv1.IngressBackend{
+											Service: &networkingv1.IngressServiceBackend{
+												Name: "hoge",
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+					{},
+				},
+			},
+		},
+		{
+			name: "invalid label key",
+			meta: metav1.ObjectMeta{
+				Labels: map[string]string{"team/": "a"},
+			},
+			wantPath: "metadata.labels[team/]",
+		},
+		{
+			name: "invalid annotation key",
+			meta: metav1.ObjectMeta{
+				Annotations: map[string]string{"example.com/owner": "a", "-owner": "b"},
+			},
+			wantPath: "metadata.annotations[-owner]",
+		},
+		{
+			name: "too long host label",
+			spec: networkingv1.IngressSpec{
+				Rules: []networkingv1.IngressRule{
+					{
+						Host: "www-" + strings.Repeat("a", 60) + ".example.com",
+					},
+				},
+			},
+			wantPath: "spec.rules[0].host",
+		},
+		{
+			name: "invalid tls host",
+			spec: networkingv1.IngressSpec{
+				TLS: []networkingv1.IngressTLS{
+					{
+						Hosts: []string{"Hoge.example.com"},
+					},
+				},
+			},
+			wantPath: "spec.tls[0].hosts[0]",
+		},
+		{
+			name: "invalid secret name",
+			spec: networkingv1.IngressSpec{
+				TLS: []networkingv1.IngressTLS{
+					{
+						SecretName: "hoge_tls",
+					},
+				},
+			},
+			wantPath: "spec.tls[0].secretName",
+		},
+		{
+			name: "invalid service name",
+			spec: networkingv1.IngressSpec{
+				DefaultBackend: &networkingv1.IngressBackend{
+					Service: &networkingv1.IngressServiceBackend{
+						Name: "1hoge",
+					},
+				},
+			},
+			wantPath: "spec.defaultBackend.service.name",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(&networkingv1.Ingress{ObjectMeta: tt.meta, Spec: tt.spec})
+			if (err != nil) != (tt.wantPath != "") {
+				t.Errorf("Validate() error = %v, wantPath %v", err, tt.wantPath)
+				return
+			}
+			if err == nil {
+				return
+			}
+			var fe *FieldError
+			if !errors.As(err, &fe) || fe.Path != tt.wantPath {
+				t.Errorf("Validate() error = %v, wantPath %v", err, tt.wantPath)
+			}
+		})
+	}
+}
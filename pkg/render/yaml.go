@@ -0,0 +1,137 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	networkingv1 "k8s.io/api/networking/v1"
+)
+
+// DecodeError reports a rendered YAML document that is not a valid IngressSpec.
+// Line is the 1-based line of the rendered document, or 0 when it is unknown.
+type DecodeError struct {
+	Line int
+	Err  error
+}
+
+func (e *DecodeError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+	}
+	return e.Err.Error()
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// decodeSpec strictly decodes a YAML document into an IngressSpec.
+func decodeSpec(data string) (*networkingv1.IngressSpec, error) {
+	spec := &networkingv1.IngressSpec{}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(data), &doc); err != nil {
+		return nil, &DecodeError{Err: err}
+	}
+	if len(doc.Content) == 0 {
+		return spec, nil
+	}
+
+	var obj interface{}
+	if err := doc.Content[0].Decode(&obj); err != nil {
+		return nil, &DecodeError{Line: doc.Content[0].Line, Err: err}
+	}
+	if _, ok := obj.(map[string]interface{}); !ok {
+		return nil, &DecodeError{Line: doc.Content[0].Line, Err: fmt.Errorf("ingress spec must be a mapping")}
+	}
+
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		return nil, &DecodeError{Err: err}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(spec); err != nil {
+		return nil, &DecodeError{Line: errorLine(doc.Content[0], err), Err: err}
+	}
+
+	return spec, nil
+}
+
+// errorLine finds the line of the YAML node that caused a JSON decode error.
+func errorLine(root *yaml.Node, err error) int {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		if n := findKey(root, strings.Split(typeErr.Field, ".")); n != nil {
+			return n.Line
+		}
+	}
+
+	const unknownField = "json: unknown field "
+	if msg := err.Error(); strings.HasPrefix(msg, unknownField) {
+		name := strings.Trim(strings.TrimPrefix(msg, unknownField), `"`)
+		if n := findAnyKey(root, name); n != nil {
+			return n.Line
+		}
+	}
+
+	return 0
+}
+
+// findKey returns the first key node matching path. Sequences are either
+// indexed by a numeric path element or searched item by item.
+func findKey(n *yaml.Node, path []string) *yaml.Node {
+	switch n.Kind {
+	case yaml.SequenceNode:
+		if i, err := strconv.Atoi(path[0]); err == nil {
+			if i >= len(n.Content) {
+				return nil
+			}
+			if len(path) == 1 {
+				return n.Content[i]
+			}
+			return findKey(n.Content[i], path[1:])
+		}
+		for _, item := range n.Content {
+			if found := findKey(item, path); found != nil {
+				return found
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value != path[0] {
+				continue
+			}
+			if len(path) == 1 {
+				return n.Content[i]
+			}
+			if found := findKey(n.Content[i+1], path[1:]); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
+
+// findAnyKey returns the first key node named name at any depth.
+func findAnyKey(n *yaml.Node, name string) *yaml.Node {
+	if n.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == name {
+				return n.Content[i]
+			}
+		}
+	}
+	for _, c := range n.Content {
+		if found := findAnyKey(c, name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
@@ -0,0 +1,264 @@
+package render
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_decodeSpec(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		want     *networkingv1.IngressSpec
+		wantLine int
+		wantErr  bool
+	}{
+		{
+			name: "default",
+			data: `
+rules:
+- host: hoge.example.com
+  http:
+    paths:
+    - path: /
+      pathType: Prefix
+      backend:
+        service:
+          name: hoge
+          port:
+            number: 80
+`,
+			want: &networkingv1.IngressSpec{
+				Rules: []networkingv1.IngressRule{
+					{
+						Host: "hoge.example.com",
+						IngressRuleValue: networkingv1.IngressRuleValue{
+							HTTP: &networkingv1.HTTPIngressRuleValue{
+								Paths: []networkingv1.HTTPIngressPath{
+									{
+										Path:     "/",
+										PathType: pathTypePtr(networkingv1.PathTypePrefix),
+										Backend: networkingv1.IngressBackend{
+											Service: &networkingv1.IngressServiceBackend{
+												Name: "hoge",
+												Port: networkingv1.ServiceBackendPort{
+													Number: 80,
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "empty",
+			data: "",
+			want: &networkingv1.IngressSpec{},
+		},
+		{
+			name: "syntax error",
+			data: `
+rules:
+- host: hoge.example.com
+ http:
+`,
+			wantErr: true,
+		},
+		{
+			name: "unknown field",
+			data: `
+rules:
+- host: hoge.example.com
+  htp: {}
+`,
+			wantLine: 4,
+			wantErr:  true,
+		},
+		{
+			name: "wrong type",
+			data: `
+rules:
+- host: hoge.example.com
+  http:
+    paths:
+    - backend:
+        service:
+          name: hoge
+          port:
+            number: eighty
+`,
+			wantLine: 10,
+			wantErr:  true,
+		},
+		{
+			name:     "not a mapping",
+			data:     "- hoge",
+			wantLine: 1,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeSpec(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("decodeSpec() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				var de *DecodeError
+				if !errors.As(err, &de) {
+					t.Errorf("decodeSpec() error = %v, want DecodeError", err)
+				} else if tt.wantLine != 0 && de.Line != tt.wantLine {
+					t.Errorf("decodeSpec() error line = %d, want %d", de.Line, tt.wantLine)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeSpec() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderYAML(t *testing.T) {
+	type args struct {
+		ing          *networkingv1.Ingress
+		specTemplate string
+		opt          Options
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    *networkingv1.Ingress
+		wantErr bool
+	}{
+		{
+			name: "range",
+			args: args{
+				ing: &networkingv1.Ingress{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{
+							"key": "{{ .Metadata.Namespace }}",
+						},
+					},
+				},
+				specTemplate: `
+{{- $ns := .Metadata.Namespace }}
+tls:
+- hosts:
+{{- range list "www" "api" }}
+  - {{ . }}-{{ $ns }}.example.com
+{{- end }}
+rules:
+{{- range list "www" "api" }}
+- host: {{ . }}-{{ $ns }}.example.com
+{{- end }}
+`,
+				opt: Options{
+					Metadata: metav1.ObjectMeta{
+						Namespace: "hoge",
+					},
+				},
+			},
+			want: &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"key": "hoge",
+					},
+				},
+				Spec: networkingv1.IngressSpec{
+					TLS: []networkingv1.IngressTLS{
+						{
+							Hosts: []string{"www-hoge.example.com", "api-hoge.example.com"},
+						},
+					},
+					Rules: []networkingv1.IngressRule{
+						{Host: "www-hoge.example.com"},
+						{Host: "api-hoge.example.com"},
+					},
+				},
+			},
+		},
+		{
+			name: "rendered output is not rendered again",
+			args: args{
+				ing: &networkingv1.Ingress{},
+				specTemplate: `
+ingressClassName: {{ "{{ .Metadata.Namespace }}" | quote }}
+`,
+			},
+			want: &networkingv1.Ingress{
+				Spec: networkingv1.IngressSpec{
+					IngressClassName: stringPtr("{{ .Metadata.Namespace }}"),
+				},
+			},
+		},
+		{
+			name: "delimiters",
+			args: args{
+				ing: &networkingv1.Ingress{},
+				specTemplate: `
+rules:
+${- range list "www" "api" }
+- host: ${ . }-${ $.Metadata.Namespace }.example.com
+${- end }
+`,
+				opt: Options{
+					Metadata: metav1.ObjectMeta{
+						Namespace: "hoge",
+					},
+					Delimiters: Delimiters{Left: "${", Right: "}"},
+				},
+			},
+			want: &networkingv1.Ingress{
+				Spec: networkingv1.IngressSpec{
+					Rules: []networkingv1.IngressRule{
+						{Host: "www-hoge.example.com"},
+						{Host: "api-hoge.example.com"},
+					},
+				},
+			},
+		},
+		{
+			name: "invalid rendered host",
+			args: args{
+				ing: &networkingv1.Ingress{},
+				specTemplate: `
+rules:
+- host: {{ .Metadata.Namespace }}_.example.com
+`,
+				opt: Options{
+					Metadata: metav1.ObjectMeta{
+						Namespace: "hoge",
+					},
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderYAML(context.Background(), tt.args.ing, tt.args.specTemplate, tt.args.opt)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RenderYAML() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RenderYAML() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func pathTypePtr(p networkingv1.PathType) *networkingv1.PathType {
+	return &p
+}